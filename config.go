@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the name of the file in the site root holding default flags
+const configFile = ".sends.yaml"
+
+// flagAliases maps short flags to their long form, so a flag given either way
+// on the command line overrides the config file
+var flagAliases = map[string]string{
+	"t": "type",
+	"c": "count",
+	"d": "dates",
+	"j": "json",
+	"o": "output",
+	"v": "verbose",
+}
+
+// applyConfig sets defaults from the YAML mapping of long flag names to values
+// at path, skipping flags given on the command line
+// A missing file is only an error if required is set
+func applyConfig(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && !required {
+			return nil
+		}
+		return err
+	}
+
+	var values map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+		if long, ok := flagAliases[f.Name]; ok {
+			set[long] = true
+		}
+	})
+
+	// Apply in a fixed order so errors are reported consistently
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		node := values[name]
+		if long, ok := flagAliases[name]; ok {
+			name = long
+		}
+		if set[name] || name == "config" {
+			continue
+		}
+
+		// Lists are accepted for comma-separated flags like exclude
+		value := node.Value
+		if node.Kind == yaml.SequenceNode {
+			items := make([]string, len(node.Content))
+			for i, item := range node.Content {
+				items[i] = item.Value
+			}
+			value = strings.Join(items, ",")
+		}
+
+		if flag.Lookup(name) == nil {
+			return fmt.Errorf("%s: unknown setting %q", path, name)
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("%s: invalid %s: %w", path, name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"sends/parser"
+)
+
+// isExcluded reports whether a directory name matches any of the glob patterns
+func isExcluded(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// linkEntry describes the target of a followed symlink under the link's name
+type linkEntry struct {
+	fs.DirEntry
+	name string
+}
+
+// Name returns the name of the link rather than its target
+func (e linkEntry) Name() string {
+	return e.name
+}
+
+// walkFollowingSymlinks walks root like filepath.WalkDir, but also descends
+// into symlinked directories, reporting paths under the link
+// Each real directory is walked once, so symlink cycles end
+func walkFollowingSymlinks(root string, fn fs.WalkDirFunc) error {
+	visited := make(map[string]bool)
+
+	var walk func(display, real string) error
+	walk = func(display, real string) error {
+		return filepath.WalkDir(real, func(path string, d fs.DirEntry, err error) error {
+			rel, _ := filepath.Rel(real, path)
+			path = filepath.Join(display, rel)
+			if err != nil {
+				return fn(path, d, err)
+			}
+
+			if d.IsDir() {
+				resolved, err := filepath.EvalSymlinks(filepath.Join(real, rel))
+				if err != nil {
+					return fn(path, d, err)
+				}
+				if visited[resolved] {
+					return fs.SkipDir
+				}
+				visited[resolved] = true
+				return fn(path, d, nil)
+			}
+
+			if d.Type()&fs.ModeSymlink == 0 {
+				return fn(path, d, nil)
+			}
+
+			// Follow the link, skipping broken links
+			target := filepath.Join(real, rel)
+			info, err := os.Stat(target)
+			if err != nil {
+				return nil
+			}
+			if !info.IsDir() {
+				return fn(path, linkEntry{fs.FileInfoToDirEntry(info), d.Name()}, nil)
+			}
+			resolved, err := filepath.EvalSymlinks(target)
+			if err != nil || visited[resolved] {
+				return nil
+			}
+			if err := fn(path, linkEntry{fs.FileInfoToDirEntry(info), d.Name()}, nil); err != nil {
+				if err == fs.SkipDir {
+					return nil
+				}
+				return err
+			}
+			return walk(path, resolved)
+		})
+	}
+
+	resolved, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	return walk(root, resolved)
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// fileResult is the outcome of parsing a single content file
+type fileResult struct {
+	sends     []parser.Send
+	unmatched []string
+	warnings  []string
+	err       error
+
+	// noFrontmatter is set for files without front matter, which have no sends
+	noFrontmatter bool
+}
+
+// parseFiles extracts the sends from each path using a pool of workers
+// Results are returned in the same order as paths so output stays deterministic
+func parseFiles(paths []string, jobs int, opts parser.Options) []fileResult {
+	results := make([]fileResult, len(paths))
+	if jobs < 1 {
+		jobs = 1
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				file, err := parser.ParseFile(paths[i], opts)
+				results[i] = fileResult{
+					sends:         file.Sends,
+					unmatched:     file.Unmatched,
+					warnings:      file.Skipped,
+					err:           err,
+					noFrontmatter: file.NoFrontmatter,
+				}
+			}
+		}()
+	}
+
+	for i := range paths {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"sends/parser"
+)
+
+// BenchmarkParseFiles parses a few thousand generated posts serially and with
+// a worker per CPU (at least four, since reads overlap even on one CPU), for
+// comparing the two
+func BenchmarkParseFiles(b *testing.B) {
+	dir := b.TempDir()
+	paths := make([]string, 3000)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("post-%d", i), "index.md")
+		if err := os.MkdirAll(filepath.Dir(paths[i]), 0o755); err != nil {
+			b.Fatal(err)
+		}
+		content := fmt.Sprintf("---\ndate: 2024-05-%02d\nsends:\n  - red V%d\n  - blue 5.1%da flash\n  - green 6B+\n---\nbody\n", i%28+1, i%10, i%5)
+		if err := os.WriteFile(paths[i], []byte(content), 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	for _, jobs := range []int{1, max(4, runtime.GOMAXPROCS(0))} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			for b.Loop() {
+				parseFiles(paths, jobs, parser.Options{})
+			}
+		})
+	}
+}
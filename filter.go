@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"sends/parser"
+)
+
+// limitRows returns the first n rows, or all of them if n is not positive
+func limitRows[T any](rows []T, n int) []T {
+	if n > 0 && len(rows) > n {
+		return rows[:n]
+	}
+	return rows
+}
+
+// dedupeSends removes sends identical to an earlier one, keeping the first occurrence
+func dedupeSends(sends []parser.Send) []parser.Send {
+	seen := make(map[parser.Send]bool)
+	return filterSends(sends, func(send parser.Send) bool {
+		if seen[send] {
+			return false
+		}
+		seen[send] = true
+		return true
+	})
+}
+
+// filterSends returns the sends for which keep returns true
+func filterSends(sends []parser.Send, keep func(parser.Send) bool) []parser.Send {
+	var filtered []parser.Send
+	for _, send := range sends {
+		if keep(send) {
+			filtered = append(filtered, send)
+		}
+	}
+	return filtered
+}
+
+// matchColor reports whether a send's color contains the filter, ignoring case
+// and whitespace, so "lightblue" matches "Light Blue"
+// With fuzzy set, a color within a few edits of the filter (one per three
+// characters of the longer, at least one) also matches, so "lt blue" matches
+// "light blue"
+func matchColor(color, filter string, fuzzy bool) bool {
+	color, filter = normalizeColor(color), normalizeColor(filter)
+	if strings.Contains(color, filter) {
+		return true
+	}
+	if !fuzzy {
+		return false
+	}
+	limit := max(1, max(len([]rune(color)), len([]rune(filter)))/3)
+	return editDistance(color, filter) <= limit
+}
+
+// normalizeColor lowercases a color and removes its whitespace
+func normalizeColor(color string) string {
+	return strings.Join(strings.Fields(strings.ToLower(color)), "")
+}
+
+// gradeRange is an inclusive span of grade values, or a single grade that
+// ParseGrade can't order, which only matches itself
+type gradeRange struct {
+	lo, hi float64
+	grade  string
+}
+
+// parseGradeRange parses a single grade ("V5") or an inclusive range ("V3..V6")
+func parseGradeRange(s string) gradeRange {
+	from, to, isRange := strings.Cut(s, "..")
+	if !isRange {
+		// Unknown grades all share one value, so compare them by name
+		grade := parser.NormalizeGrade(canonicalGrade(strings.TrimSpace(from)))
+		if _, ordered := gradesOrder[grade]; !ordered && parser.Discipline(grade) == "unknown" {
+			return gradeRange{grade: grade}
+		}
+		to = from
+	}
+
+	lo := gradeValue(strings.TrimSpace(from))
+	hi := gradeValue(strings.TrimSpace(to))
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	return gradeRange{lo: lo, hi: hi}
+}
+
+// contains reports whether grade falls within the range
+func (r gradeRange) contains(grade string) bool {
+	if r.grade != "" {
+		return parser.NormalizeGrade(canonicalGrade(grade)) == r.grade
+	}
+	g := gradeValue(grade)
+	return g >= r.lo && g <= r.hi
+}
+
+// dateRange is an inclusive span of dates; a zero bound is open
+type dateRange struct {
+	since, until time.Time
+}
+
+// parseDateRange parses an inclusive range of dates like
+// "2024-04-01..2024-04-30", either end of which may be left open
+func parseDateRange(s string) (dateRange, error) {
+	from, to, ok := strings.Cut(s, "..")
+	if !ok {
+		return dateRange{}, fmt.Errorf("expected from..to: %s", s)
+	}
+
+	var r dateRange
+	var err error
+	if from = strings.TrimSpace(from); from != "" {
+		if r.since, err = time.Parse(dateLayout, from); err != nil {
+			return dateRange{}, fmt.Errorf("invalid date: %s", from)
+		}
+	}
+	if to = strings.TrimSpace(to); to != "" {
+		if r.until, err = time.Parse(dateLayout, to); err != nil {
+			return dateRange{}, fmt.Errorf("invalid date: %s", to)
+		}
+	}
+	return r, nil
+}
+
+// inDateRange reports whether date falls within the inclusive range; a zero bound is open
+// Dates that cannot be parsed are never in range
+func inDateRange(date string, since, until time.Time) bool {
+	t, err := time.Parse(dateLayout, date)
+	if err != nil {
+		return false
+	}
+	if !since.IsZero() && t.Before(since) {
+		return false
+	}
+	if !until.IsZero() && t.After(until) {
+		return false
+	}
+	return true
+}
+
+// suggestGrades returns up to n grades among the sends that look like a typo
+// of grade, closest first: those a few edits away (one per three characters,
+// at least one), or sharing a prefix
+func suggestGrades(grade string, sends []parser.Send, n int) []string {
+	grade = strings.ToLower(parser.NormalizeGrade(grade))
+
+	distances := make(map[string]int)
+	for _, send := range sends {
+		candidate := strings.ToLower(send.Grade)
+		if _, seen := distances[send.Grade]; seen || candidate == "" {
+			continue
+		}
+		d := editDistance(grade, candidate)
+		if d > 1 && (strings.HasPrefix(candidate, grade) || strings.HasPrefix(grade, candidate)) {
+			d = 1
+		}
+		distances[send.Grade] = d
+	}
+
+	limit := max(1, len([]rune(grade))/3)
+	var suggestions []string
+	for g, d := range distances {
+		if d <= limit {
+			suggestions = append(suggestions, g)
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		di, dj := distances[suggestions[i]], distances[suggestions[j]]
+		if di != dj {
+			return di < dj
+		}
+		return gradeValue(suggestions[i]) < gradeValue(suggestions[j])
+	})
+	return limitRows(suggestions, n)
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
@@ -2,211 +2,652 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
+	"runtime"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
-	"gopkg.in/yaml.v3"
+	"sends/parser"
 )
 
-type Send struct {
-	Color string
-	Grade string
-	Meta  string
-	Date  string
-}
+// dateLayout is the frontmatter date format (YYYY-MM-DD)
+const dateLayout = "2006-01-02"
+
+// options holds the command-line flags and the values derived from them
+type options struct {
+	contentType     string
+	countMode       bool
+	datesGrade      string
+	jsonMode        bool
+	csvMode         bool
+	colorFilter     string
+	sinceStr        string
+	untilStr        string
+	stdinMode       bool
+	noTotal         bool
+	jobs            int
+	maxMode         bool
+	minMode         bool
+	gradeFilter     string
+	minGrade        string
+	markdownMode    bool
+	tsvMode         bool
+	ndjsonMode      bool
+	dateField       string
+	sendsField      string
+	pyramidMode     bool
+	pyramidWidth    int
+	gradesOrderPath string
+	aliasesPath     string
+	byColorMode     bool
+	byMonthMode     bool
+	byLocationMode  bool
+	byWeekMode      bool
+	calendarYear    int
+	disciplinesMode bool
+	faMode          bool
+	progressionMode bool
+	noColorField    bool
+	plateauMode     bool
+	repeatsMode     bool
+	scoreMode       bool
+	diffStr         string
+	scoreByDay      bool
+	pointsPath      string
+	percentilesMode bool
+	followSymlinks  bool
+	topColors       int
+	excludeColor    string
+	fuzzyColor      bool
+	excludeGrade    string
+	gradeStreak     string
+	maxDanger       string
+	strictMode      bool
+	countDatesMode  bool
+	rankMode        bool
+	sortKey         string
+	reverseSort     bool
+	sortCountBy     string
+	dedupe          bool
+	limit           int
+	statsMode       bool
+	excludeDirs     string
+	filenames       string
+	projectsMode    bool
+	includeProjects bool
+	streakMode      bool
+	outputPath      string
+	minCount        int
+	totalShown      bool
+	configPath      string
+	firstsMode      bool
+	gapsMode        bool
+	pattern         string
+	avgTriesMode    bool
+	colorOutput     bool
+	bodyMode        bool
+	validateMode    bool
+	mergeMode       bool
+	gradeOnly       bool
+	trimTrailing    bool
+	convertTo       string
+	watchMode       bool
+	verbose         bool
+	templateStr     string
+	header          bool
 
-type Frontmatter struct {
-	Date  string   `yaml:"date"`
-	Sends []string `yaml:"sends"`
+	// Derived from the flags after parsing
+	sitePaths []string
+	since     time.Time
+	until     time.Time
+	diff      [2]dateRange
+	format    string
+	fields    parser.Options
+	tmpl      *template.Template
 }
 
-// parseGrade extracts numeric value for sorting
-// Sorting order: point grades (900, 1000, ...) < unknown grades (?, ??, 5.?) < rope grades (5.x) < boulder grades (Vx)
-func parseGrade(grade string) float64 {
-	// Handle question marks and unknown grades
-	if strings.Contains(grade, "?") {
-		return 10000.0 // Sort after point grades but before rope grades
+func main() {
+	var o options
+
+	// CLI flags - define both short and long forms
+
+	flag.StringVar(&o.contentType, "t", "posts", "content type(s) to parse, comma-separated")
+	flag.StringVar(&o.contentType, "type", "posts", "content type(s) to parse, comma-separated")
+	flag.BoolVar(&o.countMode, "c", false, "output counts instead of list")
+	flag.BoolVar(&o.countMode, "count", false, "output counts instead of list")
+	flag.BoolVar(&o.rankMode, "rank", false, "output counts ranked from most to fewest sends")
+	flag.StringVar(&o.datesGrade, "d", "", "output unique dates for posts with this grade")
+	flag.StringVar(&o.datesGrade, "dates", "", "output unique dates for posts with this grade")
+	flag.BoolVar(&o.jsonMode, "j", false, "output JSON instead of text")
+	flag.BoolVar(&o.jsonMode, "json", false, "output JSON instead of text")
+	flag.BoolVar(&o.csvMode, "csv", false, "output CSV with a header row")
+	flag.StringVar(&o.colorFilter, "color", "", "only include sends whose color contains this string")
+	flag.StringVar(&o.excludeColor, "exclude-color", "", "leave out sends whose color contains this string")
+	flag.BoolVar(&o.fuzzyColor, "fuzzy", false, "let --color and --exclude-color match colors a few typos away")
+	flag.StringVar(&o.sinceStr, "since", "", "only include sends on or after this date (YYYY-MM-DD)")
+	flag.StringVar(&o.untilStr, "until", "", "only include sends on or before this date (YYYY-MM-DD)")
+	flag.BoolVar(&o.stdinMode, "stdin", false, "read file paths from stdin instead of walking the site")
+	flag.BoolVar(&o.noTotal, "no-total", false, "omit the total line in count mode")
+	flag.IntVar(&o.jobs, "jobs", runtime.GOMAXPROCS(0), "number of files to parse concurrently")
+	flag.BoolVar(&o.maxMode, "max", false, "output only the highest graded send")
+	flag.BoolVar(&o.minMode, "min", false, "output only the lowest graded send")
+	flag.StringVar(&o.gradeFilter, "grade", "", "only include sends at this grade or range of grades (e.g. V3..V6)")
+	flag.StringVar(&o.minGrade, "min-grade", "", "only include sends at this grade or harder")
+	flag.StringVar(&o.excludeGrade, "exclude-grade", "", "leave out sends at this grade or range of grades")
+	flag.BoolVar(&o.markdownMode, "markdown", false, "output a markdown table")
+	flag.BoolVar(&o.tsvMode, "tsv", false, "output tab-separated values instead of text")
+	flag.BoolVar(&o.ndjsonMode, "ndjson", false, "output one JSON object per send per line")
+	flag.StringVar(&o.dateField, "date-field", "date", "frontmatter field(s) to read the date from, comma-separated")
+	flag.StringVar(&o.sendsField, "sends-field", "sends", "frontmatter field(s) to read the sends list from, comma-separated")
+	flag.BoolVar(&o.pyramidMode, "pyramid", false, "output a bar chart of counts per grade")
+	flag.IntVar(&o.pyramidWidth, "width", 40, "width of the largest bar in pyramid mode")
+	flag.StringVar(&o.gradesOrderPath, "grades-order", "", "file listing custom grades one per line in ascending order")
+	flag.StringVar(&o.aliasesPath, "aliases", "", "file of \"old = canonical\" lines renaming grades")
+	flag.BoolVar(&o.byColorMode, "by-color", false, "output counts per color instead of per grade")
+	flag.IntVar(&o.topColors, "top-colors", 0, "output counts for the n most frequent colors")
+	flag.BoolVar(&o.byMonthMode, "by-month", false, "output counts per month, chronologically")
+	flag.BoolVar(&o.byWeekMode, "by-week", false, "output counts per ISO week, chronologically")
+	flag.BoolVar(&o.disciplinesMode, "disciplines", false, "output counts per discipline: boulder, rope, point and unknown")
+	flag.IntVar(&o.calendarYear, "calendar", 0, "output a calendar of the days with sends in this year")
+	flag.BoolVar(&o.byLocationMode, "by-location", false, "output counts per location from the front matter")
+	flag.BoolVar(&o.strictMode, "strict", false, "report unparseable sends and exit non-zero if any are found")
+	flag.BoolVar(&o.countDatesMode, "count-dates", false, "output the number of sends per date")
+	flag.StringVar(&o.sortKey, "sort", "grade", "sort by grade, date or color; prefix with - to reverse")
+	flag.BoolVar(&o.reverseSort, "reverse", false, "reverse the sort order")
+	flag.StringVar(&o.sortCountBy, "sort-count-by", "grade", "in count mode, sort by grade or count")
+	flag.BoolVar(&o.dedupe, "dedupe", false, "remove identical sends logged more than once")
+	flag.IntVar(&o.limit, "limit", 0, "output at most this many rows (0 for no limit)")
+	flag.BoolVar(&o.statsMode, "stats", false, "output a summary of flashes and onsights")
+	flag.StringVar(&o.excludeDirs, "exclude", "", "skip directories whose name matches these glob patterns, comma-separated")
+	flag.BoolVar(&o.followSymlinks, "follow-symlinks", false, "walk into symlinked content directories")
+	flag.StringVar(&o.filenames, "filename", strings.Join(parser.ContentFiles, ","), "content file names or glob patterns to parse, comma-separated")
+	flag.BoolVar(&o.projectsMode, "projects", false, "only include projects, routes marked PROJECT that aren't sent yet")
+	flag.BoolVar(&o.includeProjects, "include-projects", false, "include projects alongside sends")
+	flag.StringVar(&o.maxDanger, "max-danger", "", "leave out sends with a protection rating above this one (PG13, R or X)")
+	flag.BoolVar(&o.faMode, "fa", false, "only include first ascents, sends marked FA")
+	flag.BoolVar(&o.streakMode, "streak", false, "output the longest run of consecutive days with a send")
+	flag.StringVar(&o.gradeStreak, "grade-streak", "", "output the longest run of consecutive days with a send at this grade")
+	flag.BoolVar(&o.plateauMode, "plateau", false, "output the longest span without a new hardest grade")
+	flag.BoolVar(&o.repeatsMode, "repeats", false, "output the routes sent on more than one date")
+	flag.StringVar(&o.diffStr, "diff", "", "output the change in sends per grade between two date ranges, e.g. 2024-04-01..2024-04-30,2024-05-01..2024-05-31")
+	flag.BoolVar(&o.scoreMode, "score", false, "output the total difficulty score of the sends")
+	flag.BoolVar(&o.scoreByDay, "score-by-day", false, "output the difficulty score of each day, then the total")
+	flag.StringVar(&o.pointsPath, "points", "", "file of \"grade = points\" lines overriding the built-in scores")
+	flag.StringVar(&o.outputPath, "o", "", "write output to this file instead of stdout")
+	flag.StringVar(&o.outputPath, "output", "", "write output to this file instead of stdout")
+	flag.IntVar(&o.minCount, "min-count", 1, "in count mode, hide grades with fewer sends than this")
+	flag.BoolVar(&o.totalShown, "total-shown", false, "in count mode, total only the grades shown")
+	flag.BoolVar(&o.percentilesMode, "percentiles", false, "output the grades at the 50th, 75th and 90th percentiles")
+	flag.BoolVar(&o.progressionMode, "progression", false, "output the hardest grade sent so far on each date")
+	flag.BoolVar(&o.firstsMode, "firsts", false, "output the date each grade was first sent")
+	flag.BoolVar(&o.gapsMode, "gaps", false, "output the grades never sent between the easiest and hardest sent")
+	flag.BoolVar(&o.mergeMode, "merge-equivalents", false, "count boulder and rope grades under their equivalent V-grade")
+	flag.BoolVar(&o.validateMode, "validate", false, "check every file and send parses, reporting problems instead of output")
+	flag.BoolVar(&o.bodyMode, "body", false, "also read sends from bullet lists in the content body")
+	flag.BoolVar(&o.colorOutput, "color-output", false, "color grades by discipline when writing to a terminal")
+	flag.BoolVar(&o.avgTriesMode, "avg-tries", false, "output the average tries per grade for sends that record them")
+	flag.StringVar(&o.pattern, "pattern", "", "regexp with color, grade and meta named groups used to parse sends")
+	flag.StringVar(&o.configPath, "config", "", "read default flags from this file instead of .sends.yaml in the site root")
+	flag.StringVar(&o.convertTo, "convert", "", "display grades converted to this system (v, font, french, yds)")
+	flag.BoolVar(&o.watchMode, "watch", false, "re-run whenever a content file changes")
+	flag.BoolVar(&o.verbose, "v", false, "report files skipped because of malformed front matter")
+	flag.BoolVar(&o.verbose, "verbose", false, "report files skipped because of malformed front matter")
+	flag.StringVar(&o.templateStr, "format", "", "Go template used to print each send in list mode")
+	flag.BoolVar(&o.header, "header", false, "start the output with a line listing the site and flags that produced it")
+	flag.BoolVar(&o.trimTrailing, "trim-trailing", false, "remove trailing spaces from every output line")
+	flag.BoolVar(&o.noColorField, "no-color-field", false, "leave the color out of list output")
+	flag.BoolVar(&o.gradeOnly, "grade-only", false, "in list mode, print only the grade of each send")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: sends [options] [<hugo-site-path>...]\n")
+		fmt.Fprintf(os.Stderr, "       sends [options] --stdin < paths.txt\n")
+		fmt.Fprintf(os.Stderr, "\nThe site path defaults to $SENDS_SITE if set, otherwise the current directory.\n")
+		fmt.Fprintf(os.Stderr, "A content file may be given in place of a site to parse just that file.\n")
+		fmt.Fprintf(os.Stderr, "Send entries starting with #, quoted so YAML keeps them (- \"# blue V5\"), are ignored.\n")
+		fmt.Fprintf(os.Stderr, "\nOptions:\n")
+		fmt.Fprintf(os.Stderr, "  -t, --type string           content type(s) to parse, comma-separated (default \"posts\")\n")
+		fmt.Fprintf(os.Stderr, "  -c, --count                 output counts instead of list\n")
+		fmt.Fprintf(os.Stderr, "      --rank                  output counts ranked from most to fewest sends, e.g. 1. V4 (23);\n")
+		fmt.Fprintf(os.Stderr, "                              tied grades share a rank and the next skips ahead (1, 2, 2, 4)\n")
+		fmt.Fprintf(os.Stderr, "  -d, --dates string          output unique dates for posts with this grade\n")
+		fmt.Fprintf(os.Stderr, "  -j, --json                  output JSON instead of text\n")
+		fmt.Fprintf(os.Stderr, "      --csv                   output CSV with a header row\n")
+		fmt.Fprintf(os.Stderr, "      --color string          only include sends whose color contains this string, ignoring\n")
+		fmt.Fprintf(os.Stderr, "                              case and spaces (lightblue matches Light Blue)\n")
+		fmt.Fprintf(os.Stderr, "      --exclude-color string  leave out sends whose color contains this string\n")
+		fmt.Fprintf(os.Stderr, "      --fuzzy                 let --color and --exclude-color also match colors within one edit\n")
+		fmt.Fprintf(os.Stderr, "                              per three letters (lt blue matches light blue)\n")
+		fmt.Fprintf(os.Stderr, "      --since date            only include sends on or after this date (YYYY-MM-DD)\n")
+		fmt.Fprintf(os.Stderr, "      --until date            only include sends on or before this date (YYYY-MM-DD)\n")
+		fmt.Fprintf(os.Stderr, "      --stdin                 read file paths from stdin instead of walking the site\n")
+		fmt.Fprintf(os.Stderr, "      --no-total              omit the total line in count mode\n")
+		fmt.Fprintf(os.Stderr, "      --jobs int              number of files to parse concurrently (default GOMAXPROCS)\n")
+		fmt.Fprintf(os.Stderr, "      --max                   output only the highest graded send\n")
+		fmt.Fprintf(os.Stderr, "      --min                   output only the lowest graded send\n")
+		fmt.Fprintf(os.Stderr, "      --grade string          only include sends at this grade or range of grades (e.g. V3..V6)\n")
+		fmt.Fprintf(os.Stderr, "      --min-grade string      only include sends at this grade or harder\n")
+		fmt.Fprintf(os.Stderr, "      --exclude-grade string  leave out sends at this grade or range of grades (e.g. V0..V2)\n")
+		fmt.Fprintf(os.Stderr, "      --markdown              output a markdown table\n")
+		fmt.Fprintf(os.Stderr, "      --tsv                   output tab-separated values instead of text\n")
+		fmt.Fprintf(os.Stderr, "      --ndjson                output one JSON object per send per line (reports use --json)\n")
+		fmt.Fprintf(os.Stderr, "      --date-field string     frontmatter field(s) to read the date from, comma-separated (default \"date\")\n")
+		fmt.Fprintf(os.Stderr, "      --sends-field string    frontmatter field(s) to read the sends list from, tried in order,\n")
+		fmt.Fprintf(os.Stderr, "                              comma-separated (default \"sends\"), e.g. sends,ascents,ticks\n")
+		fmt.Fprintf(os.Stderr, "      --pyramid               output a bar chart of counts per grade\n")
+		fmt.Fprintf(os.Stderr, "      --width int             width of the largest bar in pyramid mode (default 40)\n")
+		fmt.Fprintf(os.Stderr, "      --grades-order file     file listing custom grades one per line in ascending order\n")
+		fmt.Fprintf(os.Stderr, "      --aliases file          file of \"old = canonical\" lines renaming grades, e.g. 900 = Level 3\n")
+		fmt.Fprintf(os.Stderr, "      --by-color              output counts per color instead of per grade\n")
+		fmt.Fprintf(os.Stderr, "      --top-colors n          output counts for the n most frequent colors, like --by-color\n")
+		fmt.Fprintf(os.Stderr, "      --by-month              output counts per month (YYYY-MM), chronologically\n")
+		fmt.Fprintf(os.Stderr, "      --by-week               output counts per ISO week (YYYY-Www), chronologically\n")
+		fmt.Fprintf(os.Stderr, "      --disciplines           output counts per discipline: boulder, rope, point and unknown\n")
+		fmt.Fprintf(os.Stderr, "      --calendar year         output a calendar of the year with a column per week, shading\n")
+		fmt.Fprintf(os.Stderr, "                              each day by its sends: . for 1, : for 2-3, # for 4 or more\n")
+		fmt.Fprintf(os.Stderr, "      --by-location           output counts per front matter location, most frequent first\n")
+		fmt.Fprintf(os.Stderr, "      --strict                report unparseable sends and exit non-zero if any are found\n")
+		fmt.Fprintf(os.Stderr, "                              or a content directory has no content files\n")
+		fmt.Fprintf(os.Stderr, "      --count-dates           output the number of sends per date\n")
+		fmt.Fprintf(os.Stderr, "      --sort string           sort by grade, date or color; prefix with - to reverse (default \"grade\")\n")
+		fmt.Fprintf(os.Stderr, "      --reverse               reverse the sort order\n")
+		fmt.Fprintf(os.Stderr, "      --sort-count-by key     in count mode, sort by grade (easiest first) or count (most sent\n")
+		fmt.Fprintf(os.Stderr, "                              first); --reverse flips either (default \"grade\")\n")
+		fmt.Fprintf(os.Stderr, "      --dedupe                remove identical sends logged more than once\n")
+		fmt.Fprintf(os.Stderr, "      --limit int             output at most this many rows (0 for no limit)\n")
+		fmt.Fprintf(os.Stderr, "      --stats                 output a summary of flashes and onsights\n")
+		fmt.Fprintf(os.Stderr, "      --exclude patterns      skip directories whose name matches these glob patterns, comma-separated\n")
+		fmt.Fprintf(os.Stderr, "      --follow-symlinks       walk into symlinked content directories, visiting each once\n")
+		fmt.Fprintf(os.Stderr, "      --filename patterns     content file names or glob patterns to parse, comma-separated\n")
+		fmt.Fprintf(os.Stderr, "                              (default: index.md; gzipped copies are also matched)\n")
+		fmt.Fprintf(os.Stderr, "      --projects              only include projects, routes marked PROJECT that aren't sent yet\n")
+		fmt.Fprintf(os.Stderr, "      --include-projects      include projects alongside sends (excluded by default)\n")
+		fmt.Fprintf(os.Stderr, "      --max-danger rating     leave out sends with a protection rating above this one (PG13, R,\n")
+		fmt.Fprintf(os.Stderr, "                              X), e.g. R excludes X; unrated sends are always kept\n")
+		fmt.Fprintf(os.Stderr, "      --fa                    only include first ascents, sends with FA in their meta\n")
+		fmt.Fprintf(os.Stderr, "      --streak                output the longest run of consecutive days with a send\n")
+		fmt.Fprintf(os.Stderr, "      --grade-streak grade    output the longest run of consecutive days with a send at this grade\n")
+		fmt.Fprintf(os.Stderr, "      --plateau               output the longest span of days without a new hardest grade\n")
+		fmt.Fprintf(os.Stderr, "      --repeats               output the routes (same color, grade and meta) sent on more than\n")
+		fmt.Fprintf(os.Stderr, "                              one date, with their dates\n")
+		fmt.Fprintf(os.Stderr, "      --diff ranges           output the change in sends per grade from the first date range to\n")
+		fmt.Fprintf(os.Stderr, "                              the second, e.g. 2024-04-01..2024-04-30,2024-05-01..2024-05-31;\n")
+		fmt.Fprintf(os.Stderr, "                              either end of a range may be left open\n")
+		fmt.Fprintf(os.Stderr, "      --score                 output the total difficulty score of the sends, 10 points a grade:\n")
+		fmt.Fprintf(os.Stderr, "                              V0 = 10, V1 = 20, 5.6 = 10, 5.10a = 50; point grades score their value\n")
+		fmt.Fprintf(os.Stderr, "      --score-by-day          output the difficulty score of each day, then the total\n")
+		fmt.Fprintf(os.Stderr, "      --points file           file of \"grade = points\" lines overriding the built-in scores\n")
+		fmt.Fprintf(os.Stderr, "  -o, --output file           write output to this file instead of stdout, replacing it\n")
+		fmt.Fprintf(os.Stderr, "      --min-count n           in count mode, hide grades with fewer than n sends (default: 1)\n")
+		fmt.Fprintf(os.Stderr, "      --total-shown           in count mode, total only the grades shown rather than all sends\n")
+		fmt.Fprintf(os.Stderr, "      --percentiles           output the grades at the 50th, 75th and 90th percentiles\n")
+		fmt.Fprintf(os.Stderr, "      --progression           output the hardest grade sent so far on each date, chronologically\n")
+		fmt.Fprintf(os.Stderr, "      --firsts                output the date each grade was first sent, easiest grade first\n")
+		fmt.Fprintf(os.Stderr, "      --gaps                  output the grades never sent between the easiest and hardest sent\n")
+		fmt.Fprintf(os.Stderr, "                              in each of the V, Font, French and YDS ladders\n")
+		fmt.Fprintf(os.Stderr, "      --merge-equivalents     in count and pyramid modes, count Font and rope grades under their\n")
+		fmt.Fprintf(os.Stderr, "                              equivalent V-grade (V0 = 5.10, V3 = 5.12a, V6 = 5.13a, V10 = 5.14a)\n")
+		fmt.Fprintf(os.Stderr, "      --validate              check every file and send parses, reporting problems instead of\n")
+		fmt.Fprintf(os.Stderr, "                              output; exits 1 if any are found\n")
+		fmt.Fprintf(os.Stderr, "      --body                  also read sends from bullet lists (- or *) in the content body\n")
+		fmt.Fprintf(os.Stderr, "      --color-output          color grades by discipline when writing to a terminal\n")
+		fmt.Fprintf(os.Stderr, "                              (disabled when NO_COLOR is set)\n")
+		fmt.Fprintf(os.Stderr, "      --avg-tries             output the average tries per grade for sends that record them\n")
+		fmt.Fprintf(os.Stderr, "      --pattern regexp        parse sends with this regexp instead of the default, which\n")
+		fmt.Fprintf(os.Stderr, "                              must have (?P<color>...), (?P<grade>...) and (?P<meta>...) groups\n")
+		fmt.Fprintf(os.Stderr, "      --config file           read default flags from this file instead of .sends.yaml in the\n")
+		fmt.Fprintf(os.Stderr, "                              site root; keys are long flag names, e.g. type: posts,climbs\n")
+		fmt.Fprintf(os.Stderr, "      --convert system        display grades converted to this system (v, font, french, yds);\n")
+		fmt.Fprintf(os.Stderr, "                              grades with no clean equivalent are marked with a trailing ~\n")
+		fmt.Fprintf(os.Stderr, "      --watch                 re-run whenever a content file changes (Ctrl-C to exit)\n")
+		fmt.Fprintf(os.Stderr, "  -v, --verbose               report files skipped because of malformed front matter\n")
+		fmt.Fprintf(os.Stderr, "      --format template       Go template used to print each send in list mode,\n")
+		fmt.Fprintf(os.Stderr, "                              e.g. '{{.Date}} {{.Grade}} ({{.Color}})'\n")
+		fmt.Fprintf(os.Stderr, "      --header                start the output with a comment listing the site and flags that\n")
+		fmt.Fprintf(os.Stderr, "                              produced it, e.g. # sends from . type=posts color=blue; JSON\n")
+		fmt.Fprintf(os.Stderr, "                              output becomes {\"header\": {...}, \"data\": ...}\n")
+		fmt.Fprintf(os.Stderr, "      --trim-trailing         remove trailing spaces from every output line\n")
+		fmt.Fprintf(os.Stderr, "      --no-color-field        leave the color out of list output in every format\n")
+		fmt.Fprintf(os.Stderr, "      --grade-only            in list mode, print only the grade of each send\n")
+		fmt.Fprintf(os.Stderr, "\nExit status:\n")
+		fmt.Fprintf(os.Stderr, "  0  sends were found\n")
+		fmt.Fprintf(os.Stderr, "  1  usage or other error\n")
+		fmt.Fprintf(os.Stderr, "  2  no sends matched after filtering\n")
 	}
 
-	// Handle V-grades (boulder grades)
-	if strings.HasPrefix(grade, "V") {
-		g := strings.TrimPrefix(grade, "V")
-		hasPlus := strings.HasSuffix(g, "+")
-		hasMinus := strings.HasSuffix(g, "-")
-		g = strings.TrimSuffix(g, "+")
-		g = strings.TrimSuffix(g, "-")
+	flag.Parse()
 
-		val, err := strconv.ParseFloat(g, 64)
-		if err != nil {
-			return 1000000.0 // Sort unknown V-grades last
+	// Default to $SENDS_SITE, then the current directory, when no site path is given
+	o.sitePaths = flag.Args()
+	if len(o.sitePaths) == 0 {
+		o.sitePaths = []string{"."}
+		if site := os.Getenv("SENDS_SITE"); site != "" {
+			o.sitePaths = []string{site}
 		}
+	}
 
-		// Add 100000 to separate V-grades from rope grades
-		val += 100000.0
+	// Fill in defaults from the config file for flags not given on the command
+	// line, reading it from the first site; a single file has no site root
+	configPath, required := filepath.Join(o.sitePaths[0], configFile), false
+	if info, err := os.Stat(o.sitePaths[0]); err == nil && !info.IsDir() {
+		configPath = ""
+	}
+	if o.configPath != "" {
+		configPath, required = o.configPath, true
+	}
+	if configPath != "" {
+		if err := applyConfig(configPath, required); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot read config: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
-		// Add small amounts for modifiers
-		if hasPlus {
-			val += 0.1
-		} else if hasMinus {
-			val -= 0.1
+	// Parse the date range, if any
+	if o.sinceStr != "" {
+		t, err := time.Parse(dateLayout, o.sinceStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --since date: %s\n", o.sinceStr)
+			os.Exit(1)
+		}
+		o.since = t
+	}
+	if o.untilStr != "" {
+		t, err := time.Parse(dateLayout, o.untilStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --until date: %s\n", o.untilStr)
+			os.Exit(1)
 		}
+		o.until = t
+	}
 
-		return val
+	// Validate the sort key
+	if strings.HasPrefix(o.sortKey, "-") {
+		o.sortKey = strings.TrimPrefix(o.sortKey, "-")
+		o.reverseSort = !o.reverseSort
+	}
+	if o.sortCountBy != "grade" && o.sortCountBy != "count" {
+		fmt.Fprintf(os.Stderr, "Error: invalid --sort-count-by key: %s\n", o.sortCountBy)
+		os.Exit(1)
+	}
+	if o.sortKey != "grade" && o.sortKey != "date" && o.sortKey != "color" {
+		fmt.Fprintf(os.Stderr, "Error: invalid --sort key: %s\n", o.sortKey)
+		os.Exit(1)
 	}
 
-	// Handle rope grades (5.x format)
-	if strings.HasPrefix(grade, "5.") {
-		g := strings.TrimPrefix(grade, "5.")
-		hasPlus := strings.HasSuffix(g, "+")
-		hasMinus := strings.HasSuffix(g, "-")
-		g = strings.TrimSuffix(g, "+")
-		g = strings.TrimSuffix(g, "-")
+	// Validate the conversion target
+	if o.convertTo != "" && !parser.IsGradeSystem(o.convertTo) {
+		fmt.Fprintf(os.Stderr, "Error: unknown --convert system: %s\n", o.convertTo)
+		os.Exit(1)
+	}
 
-		val, err := strconv.ParseFloat(g, 64)
+	// Load the custom grade ordering, if any
+	if o.gradesOrderPath != "" {
+		order, err := loadGradesOrder(o.gradesOrderPath)
 		if err != nil {
-			return 10000.0 // Sort unknown rope grades with question marks
+			fmt.Fprintf(os.Stderr, "Error reading grades order: %v\n", err)
+			os.Exit(1)
 		}
+		gradesOrder = order
+	}
 
-		// Add 20000 to separate rope grades from point grades
-		val += 20000.0
+	// Parse the ranges to compare, if any
+	if o.diffStr != "" {
+		ranges := strings.Split(o.diffStr, ",")
+		if len(ranges) != 2 {
+			fmt.Fprintf(os.Stderr, "Error: invalid --diff: expected two date ranges separated by a comma\n")
+			os.Exit(1)
+		}
+		for i, s := range ranges {
+			r, err := parseDateRange(s)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: invalid --diff: %v\n", err)
+				os.Exit(1)
+			}
+			o.diff[i] = r
+		}
+	}
 
-		// Add small amounts for modifiers
-		if hasPlus {
-			val += 0.1
-		} else if hasMinus {
-			val -= 0.1
+	// Load the grade aliases, if any
+	if o.aliasesPath != "" {
+		aliases, err := loadGradeAliases(o.aliasesPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading grade aliases: %v\n", err)
+			os.Exit(1)
 		}
+		gradeAliases = aliases
+	}
 
-		return val
+	// Load the custom scores, if any
+	if o.pointsPath != "" {
+		points, err := loadGradePoints(o.pointsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading points: %v\n", err)
+			os.Exit(1)
+		}
+		gradePoints = points
 	}
 
-	// Handle point grades (pure numbers like 900, 1000, 1100)
-	val, err := strconv.ParseFloat(grade, 64)
-	if err != nil {
-		return 1000000.0 // Sort unknown grades last
+	// Validate the protection rating limit
+	if o.maxDanger != "" {
+		rating := strings.ReplaceAll(strings.ToUpper(o.maxDanger), "-", "")
+		if !slices.Contains(parser.DangerLevels, rating) {
+			fmt.Fprintf(os.Stderr, "Error: invalid --max-danger: %s (must be PG13, R or X)\n", o.maxDanger)
+			os.Exit(1)
+		}
+		o.maxDanger = rating
 	}
 
-	return val
-}
+	// Reject malformed patterns up front rather than silently matching nothing
+	for _, pattern := range splitList(o.excludeDirs) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --exclude pattern: %s\n", pattern)
+			os.Exit(1)
+		}
+	}
+	for _, pattern := range splitList(o.filenames) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --filename pattern: %s\n", pattern)
+			os.Exit(1)
+		}
+	}
 
-func extractFrontmatter(path string) (*Frontmatter, error) {
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-
-	// Extract frontmatter between --- delimiters
-	scanner := bufio.NewScanner(file)
-	var frontmatterLines []string
-	inFrontmatter := false
-	delimiterCount := 0
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if line == "---" {
-			delimiterCount++
-			if delimiterCount == 1 {
-				inFrontmatter = true
-				continue
-			} else if delimiterCount == 2 {
-				break
-			}
+	// Parse the list template once, before any walking
+	if o.templateStr != "" {
+		tmpl, err := template.New("format").Parse(o.templateStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --format template: %v\n", err)
+			flag.Usage()
+			os.Exit(1)
 		}
-		if inFrontmatter {
-			frontmatterLines = append(frontmatterLines, line)
+		o.tmpl = tmpl
+	}
+
+	// Match the casing grades are normalized to when parsed
+	o.datesGrade = parser.NormalizeGrade(o.datesGrade)
+
+	// Frontmatter fields to read
+	o.fields = parser.Options{DateFields: splitList(o.dateField), SendsFields: splitList(o.sendsField), Body: o.bodyMode}
+	if o.pattern != "" {
+		pattern, err := parser.CompileSendPattern(o.pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --pattern: %v\n", err)
+			os.Exit(1)
 		}
+		o.fields.Pattern = pattern
+	}
+
+	// Pick the output format
+	o.format = "text"
+	switch {
+	case o.jsonMode:
+		o.format = "json"
+	case o.csvMode:
+		o.format = "csv"
+	case o.markdownMode:
+		o.format = "markdown"
+	case o.tsvMode:
+		o.format = "tsv"
+	case o.ndjsonMode:
+		o.format = "ndjson"
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	if o.watchMode {
+		if o.stdinMode {
+			fmt.Fprintf(os.Stderr, "Error: --watch cannot be combined with --stdin\n")
+			os.Exit(1)
+		}
+		watch(&o)
+		return
 	}
 
-	// Parse YAML
-	var fm Frontmatter
-	yamlStr := strings.Join(frontmatterLines, "\n")
-	if err := yaml.Unmarshal([]byte(yamlStr), &fm); err != nil {
-		return nil, err
+	if o.validateMode {
+		os.Exit(validate(&o))
 	}
 
-	return &fm, nil
+	os.Exit(run(&o))
 }
 
-func main() {
-	// CLI flags - define both short and long forms
-	var contentType string
-	var countMode bool
-	var datesGrade string
+// validate parses every content file and reports those with malformed front
+// matter or send entries that can't be parsed, returning the exit status
+func validate(o *options) int {
+	paths, _ := collectPaths(o)
 
-	flag.StringVar(&contentType, "t", "posts", "content type to parse")
-	flag.StringVar(&contentType, "type", "posts", "content type to parse")
-	flag.BoolVar(&countMode, "c", false, "output counts instead of list")
-	flag.BoolVar(&countMode, "count", false, "output counts instead of list")
-	flag.StringVar(&datesGrade, "d", "", "output unique dates for posts with this grade")
-	flag.StringVar(&datesGrade, "dates", "", "output unique dates for posts with this grade")
+	problems := 0
+	for i, result := range parseFiles(paths, o.jobs, o.fields) {
+		if result.err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", paths[i], result.err)
+			problems++
+			continue
+		}
+		for _, sendStr := range result.unmatched {
+			fmt.Fprintf(os.Stderr, "%s: unparseable send %q\n", paths[i], sendStr)
+			problems++
+		}
+		for _, warning := range result.warnings {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", paths[i], warning)
+			problems++
+		}
+	}
 
-	flag.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: sends [options] <hugo-site-path>\n")
-		fmt.Fprintf(os.Stderr, "\nOptions:\n")
-		fmt.Fprintf(os.Stderr, "  -t, --type string   content type to parse (default \"posts\")\n")
-		fmt.Fprintf(os.Stderr, "  -c, --count         output counts instead of list\n")
-		fmt.Fprintf(os.Stderr, "  -d, --dates string  output unique dates for posts with this grade\n")
+	if problems > 0 {
+		fmt.Fprintf(os.Stderr, "%d problems found checking %d files\n", problems, len(paths))
+		return 1
 	}
+	return 0
+}
 
-	flag.Parse()
+// collectPaths returns the content files to parse, either read from stdin or
+// found by walking each content type under the site, along with the content
+// directories that held none
+func collectPaths(o *options) (paths, empty []string) {
 
-	if flag.NArg() < 1 {
-		flag.Usage()
-		os.Exit(1)
+	if o.stdinMode {
+		// Stdin mode: read newline-separated file paths instead of walking
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			path := strings.TrimSpace(scanner.Text())
+			if path != "" {
+				paths = append(paths, path)
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading stdin: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		seen := make(map[string]bool)
+		excludes := splitList(o.excludeDirs)
+		filenames := splitList(o.filenames)
+
+		// Walk each content type of each site, merging the files found
+		for _, sitePath := range o.sitePaths {
+			info, err := os.Stat(sitePath)
+			if os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "Error: site path does not exist: %s\n", sitePath)
+				os.Exit(1)
+			}
+
+			// A file given directly is parsed on its own
+			if err == nil && !info.IsDir() {
+				if !seen[sitePath] {
+					seen[sitePath] = true
+					paths = append(paths, sitePath)
+				}
+				continue
+			}
+
+			for _, t := range strings.Split(o.contentType, ",") {
+				contentPath := filepath.Join(sitePath, "content", strings.TrimSpace(t))
+				if walkContent(contentPath, o.followSymlinks, o.verbose, excludes, filenames, seen, &paths) == 0 {
+					empty = append(empty, contentPath)
+				}
+			}
+		}
 	}
 
-	sitePath := flag.Arg(0)
-	contentPath := filepath.Join(sitePath, "content", contentType)
+	return paths, empty
+}
 
+// walkContent appends the content files under contentPath to paths, skipping
+// excluded directories and files already seen, and returns how many content
+// files it matched, seen or not
+// Symlinked directories are only walked if follow is set
+// Paths that can't be read are skipped, with a warning if verbose is set; only
+// an unreadable content path itself is fatal
+func walkContent(contentPath string, follow, verbose bool, excludes, filenames []string, seen map[string]bool, paths *[]string) int {
 	// Check if content path exists
 	if _, err := os.Stat(contentPath); os.IsNotExist(err) {
 		fmt.Fprintf(os.Stderr, "Error: content path does not exist: %s\n", contentPath)
+		if flag.NArg() == 0 && os.Getenv("SENDS_SITE") == "" {
+			// Probably not run from a site root; show how to pass one
+			flag.Usage()
+		}
 		os.Exit(1)
 	}
 
-	// Regex pattern matches the bash scripts
-	pattern := regexp.MustCompile(`(?P<color>[\w\s']*?\s?)(?P<grade>V?[\d.+?-]+)(?P<meta>\s?.*)`)
-
-	var sends []Send
+	walk := filepath.WalkDir
+	if follow {
+		walk = walkFollowingSymlinks
+	}
 
-	// Walk directory to find all index.md files
-	err := filepath.WalkDir(contentPath, func(path string, d fs.DirEntry, err error) error {
+	// Walk directory to find all index.md (and index.md.gz) files
+	matched := 0
+	err := walk(contentPath, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return err
+			if path == contentPath {
+				return err
+			}
+			if verbose {
+				fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", path, err)
+			}
+			return nil
 		}
 
-		if !d.IsDir() && strings.ToLower(d.Name()) == "index.md" {
-			fm, err := extractFrontmatter(path)
-			if err != nil {
-				// Skip files with parse errors
-				return nil
-			}
-
-			// Parse each send string with regex
-			for _, sendStr := range fm.Sends {
-				matches := pattern.FindStringSubmatch(sendStr)
-				if matches != nil {
-					sends = append(sends, Send{
-						Color: matches[1],
-						Grade: matches[2],
-						Meta:  matches[3],
-						Date:  fm.Date,
-					})
-				}
-			}
+		// Skip excluded directories entirely
+		if d.IsDir() && path != contentPath && isExcluded(d.Name(), excludes) {
+			return fs.SkipDir
+		}
+
+		if d.IsDir() || !parser.IsContentFile(d.Name(), filenames) {
+			return nil
+		}
+		matched++
+
+		// Skip files already found under another content type
+		if !seen[path] {
+			seen[path] = true
+			*paths = append(*paths, path)
 		}
 
 		return nil
@@ -216,69 +657,645 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Error walking directory: %v\n", err)
 		os.Exit(1)
 	}
+	return matched
+}
+
+// warnUnknownGrade warns when no send matches grade, suggesting the closest
+// grades in the data
+func warnUnknownGrade(grade string, sends []parser.Send, match func(parser.Send) bool) {
+	if slices.ContainsFunc(sends, match) {
+		return
+	}
+	if suggestions := suggestGrades(grade, sends, 3); len(suggestions) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: no sends at grade %s; did you mean %s?\n", grade, strings.Join(suggestions, ", "))
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: no sends at grade %s\n", grade)
+}
+
+// run parses, filters and outputs the sends, returning the exit status
+func run(o *options) int {
+	paths, empty := collectPaths(o)
 
-	// Sort sends by grade (numeric), then by color
-	sort.SliceStable(sends, func(i, j int) bool {
-		gi := parseGrade(sends[i].Grade)
-		gj := parseGrade(sends[j].Grade)
-		if gi != gj {
-			return gi < gj
+	// Tell an empty content directory apart from an empty result
+	for _, contentPath := range empty {
+		fmt.Fprintf(os.Stderr, "Warning: no sends found under %s\n", contentPath)
+	}
+	if len(paths) == 0 && len(empty) > 0 {
+		if o.strictMode {
+			return 1
 		}
-		return sends[i].Color < sends[j].Color
-	})
+		return 2
+	}
+
+	var sends []parser.Send
+	unparseable := 0
+
+	for i, result := range parseFiles(paths, o.jobs, o.fields) {
+		// Warn about problems with paths given explicitly on stdin or the
+		// command line, or anywhere when asked to be verbose
+		explicit := o.stdinMode || o.verbose || slices.Contains(o.sitePaths, paths[i])
+
+		if result.err != nil {
+			// Skip files with parse errors
+			if explicit {
+				fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", paths[i], result.err)
+			}
+			continue
+		}
+		sends = append(sends, result.sends...)
+
+		if result.noFrontmatter && explicit {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", paths[i], parser.ErrNoFrontmatter)
+		}
+		if o.verbose {
+			for _, warning := range result.warnings {
+				fmt.Fprintf(os.Stderr, "Warning: %s: %s\n", paths[i], warning)
+			}
+		}
+
+		// Report send strings the regex couldn't match
+		for _, sendStr := range result.unmatched {
+			if o.strictMode {
+				fmt.Fprintf(os.Stderr, "Unparseable send in %s: %q\n", paths[i], sendStr)
+			}
+			unparseable++
+		}
+	}
+
+	// Rename retired grades to their canonical label
+	if gradeAliases != nil {
+		for i := range sends {
+			sends[i].Grade = canonicalGrade(sends[i].Grade)
+		}
+	}
+
+	// Point out likely typos in a grade that matches nothing
+	if o.datesGrade != "" {
+		warnUnknownGrade(o.datesGrade, sends, func(send parser.Send) bool {
+			return send.Grade == o.datesGrade
+		})
+	}
+	if o.gradeFilter != "" && !strings.Contains(o.gradeFilter, "..") {
+		grades := parseGradeRange(o.gradeFilter)
+		warnUnknownGrade(o.gradeFilter, sends, func(send parser.Send) bool {
+			return grades.contains(send.Grade)
+		})
+	}
+
+	// Remove identical sends logged in more than one place
+	if o.dedupe {
+		sends = dedupeSends(sends)
+	}
+
+	// Projects aren't sends, so leave them out unless asked for
+	if o.projectsMode {
+		sends = filterSends(sends, func(send parser.Send) bool {
+			return send.Project
+		})
+	} else if !o.includeProjects {
+		sends = filterSends(sends, func(send parser.Send) bool {
+			return !send.Project
+		})
+	}
+
+	// Apply filters
+	if o.colorFilter != "" {
+		sends = filterSends(sends, func(send parser.Send) bool {
+			return matchColor(send.Color, o.colorFilter, o.fuzzyColor)
+		})
+	}
+	if o.gradeFilter != "" {
+		grades := parseGradeRange(o.gradeFilter)
+		sends = filterSends(sends, func(send parser.Send) bool {
+			return grades.contains(send.Grade)
+		})
+	}
+	if o.excludeColor != "" {
+		sends = filterSends(sends, func(send parser.Send) bool {
+			return !matchColor(send.Color, o.excludeColor, o.fuzzyColor)
+		})
+	}
+	if o.excludeGrade != "" {
+		grades := parseGradeRange(o.excludeGrade)
+		sends = filterSends(sends, func(send parser.Send) bool {
+			return !grades.contains(send.Grade)
+		})
+	}
+	if o.maxDanger != "" {
+		limit := slices.Index(parser.DangerLevels, o.maxDanger)
+		sends = filterSends(sends, func(send parser.Send) bool {
+			return slices.Index(parser.DangerLevels, send.Danger) <= limit
+		})
+	}
+	if o.faMode {
+		sends = filterSends(sends, func(send parser.Send) bool {
+			return send.FA
+		})
+	}
+	if o.minGrade != "" {
+		lo := gradeValue(o.minGrade)
+		sends = filterSends(sends, func(send parser.Send) bool {
+			return gradeValue(send.Grade) >= lo
+		})
+	}
+	if !o.since.IsZero() || !o.until.IsZero() {
+		sends = filterSends(sends, func(send parser.Send) bool {
+			return inDateRange(send.Date, o.since, o.until)
+		})
+	}
+
+	// Sort sends by the chosen key (grade, then color, by default)
+	sortSends(sends, o.sortKey, o.reverseSort)
+
+	// Reduce to the single hardest or easiest send
+	if (o.maxMode || o.minMode) && len(sends) > 0 {
+		sends = []parser.Send{extremeSend(sends, o.minMode)}
+	}
+
+	// Line-delimited JSON streams sends; reports keep their JSON shape
+	format := o.format
+	if format == "ndjson" {
+		format = "json"
+	}
+
+	// Write to the output file if one was given, replacing its contents
+	var w io.Writer = os.Stdout
+	if o.outputPath != "" {
+		file, err := os.Create(o.outputPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: cannot open output file: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	// Only color grades for people, not pipes and files
+	colorize := func(grade string) string { return grade }
+	if o.colorOutput && isTerminal(w) {
+		colorize = colorGrade
+	}
+
+	// Strip trailing spaces, e.g. from templates or padded columns
+	if o.trimTrailing {
+		tw := &trimWriter{w: w}
+		defer tw.Flush()
+		w = tw
+	}
+
+	// Describe what produced the output: a comment line, a first NDJSON line
+	// for streamed sends, or an object wrapping the JSON output as its data
+	streamed := false
+	if o.header {
+		header := newReportHeader(o)
+		switch {
+		case format == "json":
+			out, buf := w, &bytes.Buffer{}
+			defer func() {
+				if streamed {
+					writeNDJSON(out, []map[string]reportHeader{{"header": header}})
+					out.Write(buf.Bytes())
+					return
+				}
+				// Reports without a JSON form still get the comment line
+				if !json.Valid(buf.Bytes()) {
+					fmt.Fprintln(out, header)
+					out.Write(buf.Bytes())
+					return
+				}
+				writeJSON(out, struct {
+					Header reportHeader    `json:"header"`
+					Data   json.RawMessage `json:"data"`
+				}{header, buf.Bytes()})
+			}()
+			w = buf
+		default:
+			fmt.Fprintln(w, header)
+		}
+	}
 
-	if datesGrade != "" {
+	// Reports that filter the sends further say whether any rows are left
+	matched := len(sends) > 0
+
+	if o.datesGrade != "" {
 		// Dates mode: filter by grade and output unique dates chronologically
-		dateMap := make(map[string]bool)
-		var dates []string
-
-		// Collect unique dates for the specified grade
-		for _, send := range sends {
-			if send.Grade == datesGrade && send.Date != "" {
-				if !dateMap[send.Date] {
-					dateMap[send.Date] = true
-					dates = append(dates, send.Date)
+		dates := limitRows(uniqueDates(sends, o.datesGrade), o.limit)
+		matched = len(dates) > 0
+
+		switch format {
+		case "json":
+			writeJSON(w, dates)
+		case "text":
+			// Output dates in ISO format (YYYY-MM-DD)
+			for _, date := range dates {
+				fmt.Fprintln(w, date)
+			}
+		default:
+			records := [][]string{{"date"}}
+			for _, date := range dates {
+				records = append(records, []string{date})
+			}
+			writeRecords(w, format, records)
+		}
+	} else if o.countMode || o.rankMode {
+		// Count mode: group by grade and count
+		counted := sends
+		if o.mergeMode {
+			counted = mergeSends(sends)
+		}
+		counts := sortedCounts(counted, o.convertTo, o.sortCountBy, o.reverseSort)
+
+		// Drop grades sent fewer than --min-count times
+		if o.minCount > 1 {
+			kept := counts[:0]
+			for _, c := range counts {
+				if c.Count >= o.minCount {
+					kept = append(kept, c)
 				}
 			}
+			counts = kept
 		}
 
-		// Sort dates chronologically
-		sort.Slice(dates, func(i, j int) bool {
-			ti, erri := time.Parse("2006-01-02", dates[i])
-			tj, errj := time.Parse("2006-01-02", dates[j])
-			// If parsing fails, fall back to string comparison
-			if erri != nil || errj != nil {
-				return dates[i] < dates[j]
+		// Rank mode: most sent grades first
+		if o.rankMode {
+			rankGrades(counts)
+		}
+		counts = limitRows(counts, o.limit)
+
+		// The total covers every send unless only the shown grades are wanted
+		total := len(sends)
+		if o.totalShown {
+			total = 0
+			for _, c := range counts {
+				total += c.Count
+			}
+		}
+
+		switch {
+		case format == "json":
+			writeJSON(w, counts)
+		case format == "text" && o.rankMode:
+			for _, c := range counts {
+				fmt.Fprintf(w, "%d. %s (%d)\n", c.Rank, colorize(c.Grade), c.Count)
+			}
+		case format == "text":
+			// Output counts
+			for _, c := range counts {
+				fmt.Fprintf(w, "%7d %s\n", c.Count, colorize(c.Grade))
+			}
+
+			// Output the grand total
+			if !o.noTotal && total > 0 {
+				fmt.Fprintf(w, "%7d total\n", total)
 			}
-			return ti.Before(tj)
+		case o.rankMode:
+			records := [][]string{{"rank", "grade", "count"}}
+			for _, c := range counts {
+				records = append(records, []string{strconv.Itoa(c.Rank), c.Grade, strconv.Itoa(c.Count)})
+			}
+			writeRecords(w, format, records)
+		default:
+			records := [][]string{{"grade", "count"}}
+			for _, c := range counts {
+				records = append(records, []string{c.Grade, strconv.Itoa(c.Count)})
+			}
+			writeRecords(w, format, records)
+		}
+	} else if o.countDatesMode {
+		// Count-dates mode: count sends per date, chronologically
+		counts := countBy(filterSends(sends, func(send parser.Send) bool {
+			return send.Date != ""
+		}), func(send parser.Send) string {
+			return send.Date
+		})
+		sort.SliceStable(counts, func(i, j int) bool {
+			return dateBefore(counts[i].Label, counts[j].Label)
 		})
+		counts = limitRows(counts, o.limit)
 
-		// Output dates in ISO format (YYYY-MM-DD)
-		for _, date := range dates {
-			fmt.Println(date)
+		if format != "text" {
+			writeCounts(w, format, "date", counts)
+		} else {
+			for _, c := range counts {
+				fmt.Fprintf(w, "%s  %d\n", c.Label, c.Count)
+			}
 		}
-	} else if countMode {
-		// Count mode: group by grade and count
-		counts := make(map[string]int)
-		var gradeOrder []string
-		seen := make(map[string]bool)
+	} else if o.byColorMode || o.topColors > 0 {
+		// By-color mode: count sends per color, most frequent first
+		counts := countBy(sends, func(send parser.Send) string {
+			if color := strings.TrimSpace(send.Color); color != "" {
+				return color
+			}
+			return "(none)"
+		})
+		sort.SliceStable(counts, func(i, j int) bool {
+			if counts[i].Count != counts[j].Count {
+				return counts[i].Count > counts[j].Count
+			}
+			return counts[i].Label < counts[j].Label
+		})
+
+		// Top colors mode is by-color mode limited to the first few actual colors
+		if o.topColors > 0 {
+			counts = slices.DeleteFunc(counts, func(c labelCount) bool {
+				return c.Label == "(none)"
+			})
+			counts = limitRows(counts, o.topColors)
+		}
+		writeCounts(w, format, "color", limitRows(counts, o.limit))
+	} else if o.byLocationMode {
+		// By-location mode: count sends per location, most frequent first with
+		// unlocated sends last
+		counts := countBy(sends, func(send parser.Send) string {
+			if location := strings.TrimSpace(send.Location); location != "" {
+				return location
+			}
+			return "unknown"
+		})
+		sort.SliceStable(counts, func(i, j int) bool {
+			if (counts[i].Label == "unknown") != (counts[j].Label == "unknown") {
+				return counts[j].Label == "unknown"
+			}
+			if counts[i].Count != counts[j].Count {
+				return counts[i].Count > counts[j].Count
+			}
+			return counts[i].Label < counts[j].Label
+		})
+		writeCounts(w, format, "location", limitRows(counts, o.limit))
+	} else if o.disciplinesMode {
+		// Disciplines mode: count sends per grade band, in a fixed order
+		counts := countBy(sends, func(send parser.Send) string {
+			return parser.Discipline(send.Grade)
+		})
+		order := map[string]int{"boulder": 0, "rope": 1, "point": 2, "unknown": 3}
+		sort.SliceStable(counts, func(i, j int) bool {
+			return order[counts[i].Label] < order[counts[j].Label]
+		})
+		writeCounts(w, format, "discipline", counts)
+	} else if o.byMonthMode {
+		// By-month mode: count sends per month, chronologically with undated sends last
+		counts := countBy(sends, sendMonth)
+		sortPeriods(counts)
+		writeCounts(w, format, "month", limitRows(counts, o.limit))
+	} else if o.byWeekMode {
+		// By-week mode: count sends per ISO week, chronologically with undated sends last
+		counts := countBy(sends, sendWeek)
+		sortPeriods(counts)
+		writeCounts(w, format, "week", limitRows(counts, o.limit))
+	} else if o.avgTriesMode {
+		// Average tries mode: mean attempts per grade, easiest grade first
+		averages, untracked := averageTries(sends)
+		averages = limitRows(averages, o.limit)
+
+		switch format {
+		case "json":
+			writeJSON(w, averages)
+		case "text":
+			for _, a := range averages {
+				fmt.Fprintf(w, "%7.1f %s\n", a.Average, a.Grade)
+			}
+
+			// Note the sends that couldn't be averaged
+			if untracked > 0 {
+				fmt.Fprintf(w, "%7d untracked\n", untracked)
+			}
+		default:
+			records := [][]string{{"grade", "average", "sends"}}
+			for _, a := range averages {
+				records = append(records, []string{a.Grade, strconv.FormatFloat(a.Average, 'f', 1, 64), strconv.Itoa(a.Sends)})
+			}
+			writeRecords(w, format, records)
+		}
+	} else if o.percentilesMode {
+		// Percentiles mode: grade at the median and upper percentiles
+		rows := percentiles(sends, []int{50, 75, 90})
+
+		switch format {
+		case "json":
+			writeJSON(w, rows)
+		case "text":
+			for _, r := range rows {
+				fmt.Fprintf(w, "p%d  %s\n", r.Percentile, r.Grade)
+			}
+		default:
+			records := [][]string{{"percentile", "grade"}}
+			for _, r := range rows {
+				records = append(records, []string{strconv.Itoa(r.Percentile), r.Grade})
+			}
+			writeRecords(w, format, records)
+		}
+	} else if o.progressionMode {
+		// Progression mode: running hardest grade by date
+		rows := limitRows(progression(sends), o.limit)
+
+		switch format {
+		case "json":
+			writeJSON(w, rows)
+		case "text":
+			for _, r := range rows {
+				fmt.Fprintf(w, "%s  %s\n", r.Date, r.Grade)
+			}
+		default:
+			records := [][]string{{"date", "grade"}}
+			for _, r := range rows {
+				records = append(records, []string{r.Date, r.Grade})
+			}
+			writeRecords(w, format, records)
+		}
+	} else if o.firstsMode {
+		// Firsts mode: earliest send date of each grade
+		firsts := limitRows(firstSends(sends), o.limit)
 
-		for _, send := range sends {
-			counts[send.Grade]++
-			if !seen[send.Grade] {
-				gradeOrder = append(gradeOrder, send.Grade)
-				seen[send.Grade] = true
+		switch format {
+		case "json":
+			writeJSON(w, firsts)
+		case "text":
+			for _, f := range firsts {
+				fmt.Fprintf(w, "%s  %s\n", f.Grade, f.Date)
 			}
+		default:
+			records := [][]string{{"grade", "date"}}
+			for _, f := range firsts {
+				records = append(records, []string{f.Grade, f.Date})
+			}
+			writeRecords(w, format, records)
 		}
+	} else if o.gapsMode {
+		// Gaps mode: grades in the sent range with no sends
+		gaps := limitRows(gradeGaps(sends), o.limit)
 
-		// Output counts
-		for _, grade := range gradeOrder {
-			fmt.Printf("%7d %s\n", counts[grade], grade)
+		switch format {
+		case "json":
+			writeJSON(w, gaps)
+		case "text":
+			for _, grade := range gaps {
+				fmt.Fprintln(w, grade)
+			}
+		default:
+			records := [][]string{{"grade"}}
+			for _, grade := range gaps {
+				records = append(records, []string{grade})
+			}
+			writeRecords(w, format, records)
 		}
+	} else if o.streakMode {
+		// Streak mode: longest run of consecutive days with a send
+		writeSpan(w, format, longestStreak(sends))
+	} else if o.gradeStreak != "" {
+		// Grade streak mode: longest run of consecutive days with a send at one grade
+		grade := parser.NormalizeGrade(o.gradeStreak)
+		graded := filterSends(sends, func(send parser.Send) bool {
+			return send.Grade == grade
+		})
+		matched = len(graded) > 0
+		writeSpan(w, format, longestStreak(graded))
+	} else if o.repeatsMode {
+		// Repeats mode: routes sent on more than one date
+		repeats := limitRows(repeatedRoutes(sends), o.limit)
+
+		switch format {
+		case "json":
+			writeJSON(w, repeats)
+		case "text":
+			for _, r := range repeats {
+				route := parser.Send{Color: r.Color, Grade: colorize(r.Grade), Meta: r.Meta}
+				fmt.Fprintf(w, "%s  %s\n", route, strings.Join(r.Dates, ", "))
+			}
+		default:
+			records := [][]string{{"color", "grade", "meta", "dates"}}
+			for _, r := range repeats {
+				records = append(records, []string{r.Color, r.Grade, r.Meta, strings.Join(r.Dates, ", ")})
+			}
+			writeRecords(w, format, records)
+		}
+	} else if o.diffStr != "" {
+		// Diff mode: change in sends per grade between two date ranges
+		changes := limitRows(diffGrades(sends, o.diff[0], o.diff[1]), o.limit)
+
+		switch format {
+		case "json":
+			writeJSON(w, changes)
+		case "text":
+			for _, c := range changes {
+				fmt.Fprintf(w, "%s: %+d\n", colorize(c.Grade), c.Change)
+			}
+		default:
+			records := [][]string{{"grade", "before", "after", "change"}}
+			for _, c := range changes {
+				records = append(records, []string{c.Grade, strconv.Itoa(c.Before), strconv.Itoa(c.After), fmt.Sprintf("%+d", c.Change)})
+			}
+			writeRecords(w, format, records)
+		}
+	} else if o.scoreMode || o.scoreByDay {
+		// Score mode: sends weighted by difficulty, summed per day and overall
+		total, days := scoreSends(sends)
+
+		switch {
+		case !o.scoreByDay && format == "json":
+			writeJSON(w, map[string]float64{"total": total})
+		case !o.scoreByDay:
+			fmt.Fprintln(w, formatScore(total))
+		case format == "json":
+			writeJSON(w, limitRows(days, o.limit))
+		case format == "text":
+			for _, d := range limitRows(days, o.limit) {
+				fmt.Fprintf(w, "%7s %s\n", formatScore(d.Score), d.Date)
+			}
+			if !o.noTotal {
+				fmt.Fprintf(w, "%7s total\n", formatScore(total))
+			}
+		default:
+			records := [][]string{{"date", "score"}}
+			for _, d := range limitRows(days, o.limit) {
+				records = append(records, []string{d.Date, formatScore(d.Score)})
+			}
+			writeRecords(w, format, records)
+		}
+	} else if o.plateauMode {
+		// Plateau mode: longest span without a new hardest grade
+		writeSpan(w, format, longestPlateau(sends))
+	} else if o.calendarYear != 0 {
+		// Calendar mode: grid of a year's days shaded by sends
+		printCalendar(w, sends, o.calendarYear)
+	} else if o.statsMode {
+		// Stats mode: flash and onsight summary
+		printStats(w, sends)
+	} else if o.pyramidMode {
+		// Pyramid mode: bar chart of counts, hardest grade on top
+		counted := sends
+		if o.mergeMode {
+			counted = mergeSends(sends)
+		}
+		printPyramid(w, countGrades(convertSends(counted, o.convertTo)), o.pyramidWidth)
 	} else {
-		// List mode: output formatted sends
-		for _, send := range sends {
-			fmt.Printf("%s%s%s\n", send.Color, send.Grade, send.Meta)
+		sends = limitRows(convertSends(sends, o.convertTo), o.limit)
+
+		switch format {
+		case "json":
+			// NDJSON mode: output the sorted sends one per line
+			if o.format == "ndjson" {
+				streamed = true
+				if o.noColorField {
+					writeNDJSON(w, colorless(sends))
+					break
+				}
+				writeNDJSON(w, sends)
+				break
+			}
+
+			// JSON mode: output the sorted sends as an array
+			if sends == nil {
+				sends = []parser.Send{}
+			}
+			if o.noColorField {
+				writeJSON(w, colorless(sends))
+				break
+			}
+			writeJSON(w, sends)
+		case "text":
+			// List mode: output formatted sends
+			for _, send := range sends {
+				if o.gradeOnly {
+					fmt.Fprintln(w, send.Grade)
+					continue
+				}
+				if o.tmpl == nil {
+					if o.noColorField {
+						send.Color = ""
+					}
+					send.Grade = colorize(send.Grade)
+					fmt.Fprintln(w, send)
+					continue
+				}
+				if err := o.tmpl.Execute(w, send); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+					return 1
+				}
+				fmt.Fprintln(w)
+			}
+		default:
+			// Table modes: output the sorted sends with a header row
+			records := [][]string{{"color", "grade", "meta", "date"}}
+			for _, send := range sends {
+				records = append(records, []string{send.Color, send.Grade, send.Meta, send.Date})
+			}
+			if o.noColorField {
+				for i := range records {
+					records[i] = records[i][1:]
+				}
+			}
+			writeRecords(w, format, records)
 		}
 	}
+
+	if o.strictMode && (unparseable > 0 || len(empty) > 0) {
+		return 1
+	}
+
+	// Distinguish an empty result from success for scripts
+	if !matched {
+		return 2
+	}
+	return 0
 }
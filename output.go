@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"sends/parser"
+)
+
+// writeRecords writes a header row and data rows in the given table format
+func writeRecords(w io.Writer, format string, records [][]string) {
+	switch format {
+	case "csv":
+		writeCSV(w, records)
+	case "markdown":
+		writeMarkdown(w, records)
+	case "tsv":
+		writeTSV(w, records)
+	}
+}
+
+// writeMarkdown writes records as a GitHub-flavored markdown table to w
+// The first record is the header row
+func writeMarkdown(w io.Writer, records [][]string) {
+	if len(records) == 0 {
+		return
+	}
+
+	for i, record := range records {
+		cells := make([]string, len(record))
+		for j, field := range record {
+			// Escape pipes so they don't break the table structure
+			cells[j] = strings.ReplaceAll(field, "|", "\\|")
+
+			// Capitalize header cells
+			if i == 0 && field != "" {
+				cells[j] = strings.ToUpper(field[:1]) + field[1:]
+			}
+		}
+		fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+
+		// Separator row after the header
+		if i == 0 {
+			seps := make([]string, len(record))
+			for j := range seps {
+				seps[j] = "---"
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(seps, " | "))
+		}
+	}
+}
+
+// writeCSV writes records as CSV to w
+func writeCSV(w io.Writer, records [][]string) {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(records); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// trimWriter removes trailing spaces from each line written through it
+// Output after the last newline is held until Flush
+type trimWriter struct {
+	w    io.Writer
+	line []byte
+}
+
+// Write buffers p, writing out each completed line without trailing spaces
+func (t *trimWriter) Write(p []byte) (int, error) {
+	t.line = append(t.line, p...)
+	for {
+		i := bytes.IndexByte(t.line, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if _, err := fmt.Fprintf(t.w, "%s\n", bytes.TrimRight(t.line[:i], " ")); err != nil {
+			return 0, err
+		}
+		t.line = t.line[i+1:]
+	}
+}
+
+// Flush writes out any unterminated final line
+func (t *trimWriter) Flush() error {
+	if len(t.line) == 0 {
+		return nil
+	}
+	_, err := t.w.Write(bytes.TrimRight(t.line, " "))
+	t.line = nil
+	return err
+}
+
+// gradeColors maps each discipline to the ANSI color its grades are shown in
+var gradeColors = map[string]string{
+	"boulder": "\033[33m", // yellow
+	"rope":    "\033[36m", // cyan
+	"point":   "\033[35m", // magenta
+}
+
+// colorGrade wraps grade in the ANSI color for its discipline
+// Unknown grades are returned unchanged
+func colorGrade(grade string) string {
+	color, ok := gradeColors[parser.Discipline(grade)]
+	if !ok {
+		return grade
+	}
+	return color + grade + "\033[0m"
+}
+
+// isTerminal reports whether w is a terminal that ANSI colors can be written
+// to, treating a set NO_COLOR environment variable as a no
+func isTerminal(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// tsvEscaper replaces the characters that would break a TSV row's structure
+var tsvEscaper = strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+
+// writeTSV writes records as tab-separated values to w
+// Tabs and newlines within fields are replaced with spaces
+func writeTSV(w io.Writer, records [][]string) {
+	for _, record := range records {
+		fields := make([]string, len(record))
+		for i, field := range record {
+			fields[i] = tsvEscaper.Replace(field)
+		}
+		fmt.Fprintln(w, strings.Join(fields, "\t"))
+	}
+}
+
+// colorlessSend encodes a send as JSON without its color
+type colorlessSend struct {
+	parser.Send
+
+	// Color shadows the send's color and is always nil, so it's omitted
+	Color *struct{} `json:"color,omitempty"`
+}
+
+// colorless wraps sends so they encode as JSON without their colors
+func colorless(sends []parser.Send) []colorlessSend {
+	wrapped := make([]colorlessSend, len(sends))
+	for i, send := range sends {
+		wrapped[i] = colorlessSend{Send: send}
+	}
+	return wrapped
+}
+
+// writeNDJSON encodes each item as compact JSON on its own line to w
+func writeNDJSON[T any](w io.Writer, items []T) {
+	enc := json.NewEncoder(w)
+	for _, item := range items {
+		if err := enc.Encode(item); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// writeJSON encodes v as indented JSON to w
+func writeJSON(w io.Writer, v any) {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// reportHeader describes the sites and flags that produced a report
+type reportHeader struct {
+	Sites   []string          `json:"sites"`
+	Filters map[string]string `json:"filters"`
+}
+
+// newReportHeader collects the sites and the flags set on the command line or
+// in the config file, plus the content type, under their long names
+func newReportHeader(o *options) reportHeader {
+	h := reportHeader{Sites: o.sitePaths, Filters: map[string]string{"type": o.contentType}}
+	if o.stdinMode {
+		h.Sites = []string{"stdin"}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		name := f.Name
+		if long, ok := flagAliases[name]; ok {
+			name = long
+		}
+		if name != "header" {
+			h.Filters[name] = f.Value.String()
+		}
+	})
+	return h
+}
+
+// String formats the header as a comment line, like
+// "# sends from site type=posts color=blue", listing true flags by name alone
+func (h reportHeader) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# sends from %s type=%s", strings.Join(h.Sites, " "), h.Filters["type"])
+	for _, name := range slices.Sorted(maps.Keys(h.Filters)) {
+		value := h.Filters[name]
+		switch {
+		case name == "type":
+		case value == "true":
+			fmt.Fprintf(&b, " %s", name)
+		case strings.ContainsAny(value, " \t\"") || value == "":
+			fmt.Fprintf(&b, " %s=%q", name, value)
+		default:
+			fmt.Fprintf(&b, " %s=%s", name, value)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// gradeSystem is a grade system that grades can be converted to
+type gradeSystem struct {
+	discipline string
+	grades     []string
+	// matches reports whether a grade is already written in this system
+	matches func(grade string) bool
+}
+
+// gradeSystems lists the systems supported by ConvertGrade, keyed by name
+var gradeSystems = map[string]gradeSystem{
+	"v": {
+		discipline: "boulder",
+		grades:     vGrades(),
+		matches: func(grade string) bool {
+			_, d := equivalent(grade)
+			return d == "boulder" && !fontGrade.MatchString(grade)
+		},
+	},
+	"font": {
+		discipline: "boulder",
+		grades:     letterGrades(6, 9, "ABC", 19),
+		matches:    fontGrade.MatchString,
+	},
+	"french": {
+		discipline: "rope",
+		grades:     letterGrades(4, 9, "abc", 36),
+		matches:    frenchGrade.MatchString,
+	},
+	"yds": {
+		discipline: "rope",
+		grades:     ydsGrades(),
+		matches: func(grade string) bool {
+			_, d := equivalent(grade)
+			return d == "rope" && len(grade) > 2 && grade[:2] == "5."
+		},
+	},
+}
+
+// vGrades returns V0 through V17
+func vGrades() []string {
+	var grades []string
+	for i := 0; i <= 17; i++ {
+		grades = append(grades, fmt.Sprintf("V%d", i))
+	}
+	return grades
+}
+
+// ydsGrades returns 5.0 through 5.9, then 5.10a through 5.15d
+func ydsGrades() []string {
+	var grades []string
+	for i := 0; i <= 9; i++ {
+		grades = append(grades, fmt.Sprintf("5.%d", i))
+	}
+	for i := 10; i <= 15; i++ {
+		for _, l := range "abcd" {
+			grades = append(grades, fmt.Sprintf("5.%d%c", i, l))
+		}
+	}
+	return grades
+}
+
+// letterGrades returns French-style grades (6A, 6A+, 6B, ...) from the first
+// to the last number, stopping after n grades
+func letterGrades(first, last int, letters string, n int) []string {
+	var grades []string
+	for i := first; i <= last; i++ {
+		for _, l := range letters {
+			grades = append(grades, fmt.Sprintf("%d%c", i, l), fmt.Sprintf("%d%c+", i, l))
+		}
+	}
+	return grades[:min(n, len(grades))]
+}
+
+// ConvertGrade translates a grade to the named system (v, font, french or yds)
+// using the same equivalence tables ParseGrade sorts by
+// Grades with no clean equivalent are returned unchanged with a trailing "~"
+// and ok set to false
+func ConvertGrade(grade, system string) (converted string, ok bool) {
+	target, known := gradeSystems[system]
+	if !known {
+		return grade + "~", false
+	}
+
+	// Already in the target system
+	if target.matches(grade) {
+		return grade, true
+	}
+
+	val, discipline := equivalent(grade)
+	if discipline != target.discipline {
+		return grade + "~", false
+	}
+
+	// Pick the closest grade in the target system, allowing for the small
+	// nudges ParseGrade adds between systems
+	best, bestDiff := "", math.Inf(1)
+	for _, g := range target.grades {
+		v, _ := equivalent(g)
+		if diff := math.Abs(v - val); diff < bestDiff {
+			best, bestDiff = g, diff
+		}
+	}
+	if bestDiff > 0.05 {
+		return grade + "~", false
+	}
+
+	return best, true
+}
+
+// IsGradeSystem reports whether ConvertGrade supports the named system
+func IsGradeSystem(system string) bool {
+	_, ok := gradeSystems[system]
+	return ok
+}
+
+// GradeLadder returns the grades of the named system (v, font, french or yds),
+// easiest first, or nil if the system is unknown
+func GradeLadder(system string) []string {
+	return gradeSystems[system].grades
+}
+
+// GradeRungs returns the system a grade is written in and the positions on
+// that system's ladder the grade covers: the closest rung, or every lettered
+// rung for a YDS grade without a letter like 5.11
+// Grades in no ladder system, like British or point grades, return ok false
+func GradeRungs(grade string) (system string, rungs []int, ok bool) {
+	if strings.Contains(grade, "?") {
+		return "", nil, false
+	}
+	for _, name := range slices.Sorted(maps.Keys(gradeSystems)) {
+		if gradeSystems[name].matches(grade) {
+			system = name
+			break
+		}
+	}
+	if system == "" {
+		return "", nil, false
+	}
+
+	val, _ := equivalent(grade)
+	best, bestDiff := 0, math.Inf(1)
+	for i, g := range gradeSystems[system].grades {
+		v, _ := equivalent(g)
+		if diff := math.Abs(v - val); diff < bestDiff {
+			best, bestDiff = i, diff
+		}
+	}
+	if bestDiff > 0.5 {
+		return "", nil, false
+	}
+
+	// 5.10 through 5.15 without a letter span the whole number grade
+	if system == "yds" && letterlessYDS.MatchString(grade) {
+		return system, []int{best, best + 1, best + 2, best + 3}, true
+	}
+	return system, []int{best}, true
+}
+
+// letterlessYDS matches YDS grades from 5.10 up that leave off the letter
+var letterlessYDS = regexp.MustCompile(`^5\.1[0-5][+-]?$`)
+
+// Points returns a grade's built-in difficulty score, rising 10 points a
+// grade in each discipline: 10 for V0 and 20 for V1, 10 for 5.6 and 50 for
+// 5.10a, with Font and French grades scored as their equivalents, rounded to
+// whole points
+// Point grades score their own value and unknown grades score nothing
+func Points(grade string) float64 {
+	val, discipline := equivalent(grade)
+	switch discipline {
+	case "boulder":
+		return max(0, math.Round(10*(val+1)))
+	case "rope":
+		return max(0, math.Round(10*(val-5)))
+	case "point":
+		return val
+	}
+	return 0
+}
+
+// ropeToV is the route-to-boulder chart used by MergeGrade, giving the
+// easiest YDS grade (as a number, 5.10a = 10.0) counted as each V-grade
+// It follows the chart commonly posted in gyms: V0 = 5.10, V3 = 5.12a,
+// V6 = 5.13a, V10 = 5.14a
+var ropeToV = []struct {
+	yds   float64
+	grade string
+}{
+	{10.0, "V0"}, {11.0, "V1"}, {11.5, "V2"}, {12.0, "V3"}, {12.25, "V4"},
+	{12.5, "V5"}, {13.0, "V6"}, {13.25, "V7"}, {13.5, "V8"}, {13.75, "V9"},
+	{14.0, "V10"}, {14.25, "V11"}, {14.5, "V12"}, {14.75, "V13"}, {15.0, "V14"},
+	{15.25, "V15"}, {15.5, "V16"}, {15.75, "V17"},
+}
+
+// MergeGrade returns the V-grade that a boulder or rope grade is counted as
+// when merging equivalents, using ropeToV for rope grades
+// Rope grades easier than 5.10a, point grades and unknown grades are returned
+// unchanged
+func MergeGrade(grade string) string {
+	val, discipline := equivalent(grade)
+	switch discipline {
+	case "boulder":
+		// Font grades between two V-grades count as the easier one
+		if converted, ok := ConvertGrade(grade, "v"); ok {
+			return converted
+		}
+		return fmt.Sprintf("V%d", int(math.Floor(val)))
+	case "rope":
+		merged := grade
+		for _, step := range ropeToV {
+			if val+0.05 < step.yds {
+				break
+			}
+			merged = step.grade
+		}
+		return merged
+	}
+	return grade
+}
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SendEntry is one item of the front matter sends list, written either as a
+// freeform string like "blue V5 flash" or as a mapping like
+// {grade: V5, color: blue, style: flash}
+// A string starting with "#", quoted so YAML keeps it, like "# blue V5", is
+// commented out and ignored
+type SendEntry struct {
+	// Text holds the string form, parsed later with the send regex
+	Text string
+
+	// Send holds the mapping form, with its fields set directly
+	Send *Send
+
+	// Skipped explains why an entry that is neither form was ignored
+	Skipped string
+}
+
+// Commented reports whether the entry is a string commented out with "#"
+func (e SendEntry) Commented() bool {
+	return e.Send == nil && strings.HasPrefix(strings.TrimSpace(e.Text), "#")
+}
+
+// sendObject is the mapping form of a send entry
+type sendObject struct {
+	Color string `yaml:"color" json:"color"`
+	Grade string `yaml:"grade" json:"grade"`
+	Meta  string `yaml:"meta" json:"meta"`
+	Date  string `yaml:"date" json:"date"`
+	Tries int    `yaml:"tries" json:"tries"`
+	Style string `yaml:"style" json:"style"`
+
+	Project  bool   `yaml:"project" json:"project"`
+	FA       bool   `yaml:"fa" json:"fa"`
+	Danger   string `yaml:"danger" json:"danger"`
+	Location string `yaml:"location" json:"location"`
+}
+
+// send converts the mapping form into a Send, reading tries and style from
+// the meta when they aren't given explicitly
+func (o sendObject) send() *Send {
+	tries, style := parseMeta(o.Meta)
+	if o.Tries != 0 {
+		tries = o.Tries
+	}
+	// Accept only the styles parseMeta recognizes, lowercased the same way
+	switch s := strings.ToLower(strings.TrimSpace(o.Style)); s {
+	case "flash", "onsight":
+		style = s
+	}
+	danger := parseDanger(o.Meta)
+	if o.Danger != "" {
+		danger = strings.ReplaceAll(strings.ToUpper(o.Danger), "-", "")
+	}
+	return &Send{
+		Color: o.Color,
+		Grade: NormalizeGrade(o.Grade),
+		Meta:  o.Meta,
+		Date:  o.Date,
+		Tries: tries,
+		Style: style,
+
+		Project:  o.Project || projectPattern.MatchString(o.Meta),
+		FA:       o.FA || faPattern.MatchString(o.Meta),
+		Danger:   danger,
+		Location: o.Location,
+	}
+}
+
+// String returns the entry as it would be written in string form
+func (e SendEntry) String() string {
+	if e.Send != nil {
+		return e.Send.String()
+	}
+	return e.Text
+}
+
+// UnmarshalYAML accepts either a scalar send string or a send mapping,
+// following aliases to anchored entries
+// Other nodes are skipped rather than failing the whole front matter
+func (e *SendEntry) UnmarshalYAML(node *yaml.Node) error {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Decode(&e.Text)
+	case yaml.MappingNode:
+		var o sendObject
+		if err := node.Decode(&o); err != nil {
+			return err
+		}
+		e.Send = o.send()
+		return nil
+	default:
+		e.Skipped = fmt.Sprintf("skipping send that is not a string or a mapping: %s", node.ShortTag())
+		return nil
+	}
+}
+
+// UnmarshalJSON accepts either a send string or a send object
+// Other values are skipped rather than failing the whole front matter
+func (e *SendEntry) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &e.Text); err == nil {
+		return nil
+	}
+
+	var o sendObject
+	if err := json.Unmarshal(data, &o); err != nil {
+		e.Skipped = fmt.Sprintf("skipping send that is not a string or an object: %s", data)
+		return nil
+	}
+	e.Send = o.send()
+	return nil
+}
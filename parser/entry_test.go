@@ -0,0 +1,98 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExtractFrontmatterAliases(t *testing.T) {
+	content := `---
+date: 2024-05-01
+project: &proj red V6 project
+sends:
+  - &warmup blue V2 flash
+  - *warmup
+  - *proj
+  - &grid {color: green, grade: V4}
+  - *grid
+---
+`
+
+	fm, err := ExtractFrontmatter(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatalf("ExtractFrontmatter: %v", err)
+	}
+
+	sends, unmatched := ParseSends(fm, Options{})
+	var got []string
+	for _, send := range sends {
+		got = append(got, send.String())
+	}
+	want := []string{"blue V2 flash", "blue V2 flash", "red V6 project", "green V4", "green V4"}
+	if !slices.Equal(got, want) || len(unmatched) != 0 {
+		t.Errorf("ParseSends = %q, unmatched %q; want %q", got, unmatched, want)
+	}
+}
+
+func TestExtractFrontmatterSkipsNonScalars(t *testing.T) {
+	content := `---
+date: 2024-05-01
+lists: &list [red V1, blue V2]
+sends:
+  - green V3
+  - *list
+  - [pink V4]
+---
+`
+
+	fm, err := ExtractFrontmatter(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatalf("ExtractFrontmatter: %v", err)
+	}
+
+	var skipped int
+	for _, entry := range fm.Sends {
+		if entry.Skipped != "" {
+			skipped++
+			if !strings.Contains(entry.Skipped, "!!seq") {
+				t.Errorf("Skipped = %q, want it to name the !!seq tag", entry.Skipped)
+			}
+		}
+	}
+	if skipped != 2 {
+		t.Errorf("skipped %d entries, want 2", skipped)
+	}
+
+	sends, unmatched := ParseSends(fm, Options{})
+	if len(sends) != 1 || sends[0].String() != "green V3" || len(unmatched) != 0 {
+		t.Errorf("ParseSends = %v, unmatched %q; want only green V3", sends, unmatched)
+	}
+}
+
+func TestExtractFrontmatterMappingStyle(t *testing.T) {
+	content := `---
+date: 2024-05-01
+sends:
+  - {grade: 6B+, style: Flash}
+  - {grade: V4, style: " ONSIGHT "}
+  - {grade: V5, style: redpoint}
+  - {grade: V6, meta: flash, style: Redpoint}
+---
+`
+
+	fm, err := ExtractFrontmatter(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatalf("ExtractFrontmatter: %v", err)
+	}
+
+	sends, _ := ParseSends(fm, Options{})
+	var got []string
+	for _, send := range sends {
+		got = append(got, send.Style)
+	}
+	want := []string{"flash", "onsight", "", "flash"}
+	if !slices.Equal(got, want) {
+		t.Errorf("styles = %q, want %q", got, want)
+	}
+}
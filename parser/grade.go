@@ -0,0 +1,349 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// frenchGrade matches French sport grades like 6a, 7b+ and 8c
+var frenchGrade = regexp.MustCompile(`^(\d)([abc])(\+?)$`)
+
+// frenchToYDS maps French sport grades to a rough YDS equivalent, indexed
+// in half-grade steps starting at 4a (4a, 4a+, 4b, 4b+, ...)
+// YDS letter grades are expressed as fractions: 5.10a = 10.0, 5.10b = 10.25, ...
+var frenchToYDS = []float64{
+	5.0, 5.5, 6.0, 6.5, 7.0, 7.5, // 4a - 4c+
+	8.0, 8.5, 9.0, 9.5, 9.75, 9.9, // 5a - 5c+
+	10.0, 10.25, 10.5, 10.75, 11.0, 11.25, // 6a - 6c+
+	11.75, 12.0, 12.25, 12.5, 12.75, 13.0, // 7a - 7c+
+	13.25, 13.5, 13.75, 14.0, 14.25, 14.5, // 8a - 8c+
+	14.75, 15.0, 15.25, 15.5, 15.75, 16.0, // 9a - 9c+
+}
+
+// parseFrenchGrade returns the YDS-equivalent value of a French sport grade
+func parseFrenchGrade(grade string) (float64, bool) {
+	m := frenchGrade.FindStringSubmatch(grade)
+	if m == nil {
+		return 0, false
+	}
+
+	// Half-grade steps relative to 4a
+	step := (int(m[1][0])-'4')*6 + int(m[2][0]-'a')*2
+	if m[3] == "+" {
+		step++
+	}
+
+	// Extrapolate for grades outside the table
+	if step < 0 {
+		return frenchToYDS[0] + float64(step)*0.5, true
+	}
+	if step >= len(frenchToYDS) {
+		last := len(frenchToYDS) - 1
+		return frenchToYDS[last] + float64(step-last)*0.25, true
+	}
+
+	return frenchToYDS[step], true
+}
+
+// fontGrade matches Fontainebleau boulder grades like 6B, 7A and 7C+
+var fontGrade = regexp.MustCompile(`^(\d)([ABC])(\+?)$`)
+
+// fontToV maps Font grades to a rough V-grade equivalent, indexed in
+// half-grade steps starting at 6A (6A, 6A+, 6B, 6B+, ...)
+var fontToV = []float64{
+	3.0, 3.25, 4.0, 4.25, 5.0, 5.25, // 6A - 6C+
+	6.0, 7.0, 8.0, 8.25, 9.0, 10.0, // 7A - 7C+
+	11.0, 12.0, 13.0, 14.0, 15.0, 16.0, // 8A - 8C+
+	17.0, // 9A
+}
+
+// parseFontGrade returns the V-grade-equivalent value of a Font grade
+func parseFontGrade(grade string) (float64, bool) {
+	m := fontGrade.FindStringSubmatch(grade)
+	if m == nil {
+		return 0, false
+	}
+
+	// Half-grade steps relative to 6A
+	step := (int(m[1][0])-'6')*6 + int(m[2][0]-'A')*2
+	if m[3] == "+" {
+		step++
+	}
+
+	// Extrapolate for grades outside the table
+	if step < 0 {
+		return fontToV[0] + float64(step)*0.5, true
+	}
+	if step >= len(fontToV) {
+		last := len(fontToV) - 1
+		return fontToV[last] + float64(step-last), true
+	}
+
+	return fontToV[step], true
+}
+
+// uiaaGrade matches UIAA grades written as roman numerals like VII, VII+ and VIII-
+var uiaaGrade = regexp.MustCompile(`^(XI{0,2}|IX|VI{0,3}|IV|I{1,3})([+-]?)$`)
+
+// uiaaNumerals lists the UIAA roman numerals in ascending order
+var uiaaNumerals = []string{"I", "II", "III", "IV", "V", "VI", "VII", "VIII", "IX", "X", "XI", "XII"}
+
+// uiaaToYDS maps each UIAA numeral to a rough YDS equivalent, in the same
+// order as uiaaNumerals
+var uiaaToYDS = []float64{2.0, 3.0, 4.0, 5.0, 6.0, 7.5, 10.0, 11.25, 12.25, 13.25, 14.0, 14.75}
+
+// parseUIAAGrade returns the YDS-equivalent value of a UIAA grade
+func parseUIAAGrade(grade string) (float64, bool) {
+	m := uiaaGrade.FindStringSubmatch(grade)
+	if m == nil {
+		return 0, false
+	}
+
+	var i int
+	for i = range uiaaNumerals {
+		if uiaaNumerals[i] == m[1] {
+			break
+		}
+	}
+	val := uiaaToYDS[i]
+
+	// Modifiers move a third of the way towards the neighbouring numeral
+	switch {
+	case m[2] == "+" && i < len(uiaaToYDS)-1:
+		val += (uiaaToYDS[i+1] - val) / 3
+	case m[2] == "+":
+		val += 0.25
+	case m[2] == "-" && i > 0:
+		val -= (val - uiaaToYDS[i-1]) / 3
+	case m[2] == "-":
+		val -= 0.25
+	}
+
+	return val, true
+}
+
+// britishGrade matches British trad grades: an adjectival grade like HVS or E2,
+// optionally followed by a technical grade like 5a
+var britishGrade = regexp.MustCompile(`^(M|D|HD|VD|HVD|MS|S|HS|MVS|VS|HVS|E(\d{1,2}))(?:\s+([4-7])([abc]))?$`)
+
+// britishToYDS maps adjectival grades below E1 to a rough YDS equivalent
+var britishToYDS = map[string]float64{
+	"M":   3.0,
+	"D":   4.0,
+	"HD":  4.5,
+	"VD":  5.0,
+	"HVD": 5.5,
+	"MS":  5.75,
+	"S":   6.0,
+	"HS":  7.0,
+	"MVS": 7.25,
+	"VS":  7.5,
+	"HVS": 9.0,
+}
+
+// parseBritishGrade returns the YDS-equivalent value of a British trad grade
+// The adjectival grade sets the value; the technical grade only breaks ties
+func parseBritishGrade(grade string) (float64, bool) {
+	m := britishGrade.FindStringSubmatch(grade)
+	if m == nil {
+		return 0, false
+	}
+
+	var val float64
+	if m[2] != "" {
+		// E-grades step by roughly half a YDS number grade from E1 = 5.10b
+		n, _ := strconv.Atoi(m[2])
+		val = 10.25 + float64(n-1)*0.5
+	} else {
+		val = britishToYDS[m[1]]
+	}
+
+	// Technical grade as a tiebreaker (4a = 0, 4b = 1, ...)
+	if m[3] != "" {
+		tech := int(m[3][0]-'4')*3 + int(m[4][0]-'a')
+		val += float64(tech+1) * 0.0005
+	}
+
+	return val, true
+}
+
+// slashGrade matches a grade range for an uncertain grade like V5/6, 5.10/11
+// or 5.10a/b, where the second grade is abbreviated
+var slashGrade = regexp.MustCompile(`^(V|5\.)(\d+)([a-d]?[+-]?)/(\d+)?([a-d]?[+-]?)$`)
+
+// parseSlashGrade returns the sort value midway between the two ends of a
+// slash grade range
+func parseSlashGrade(grade string) (float64, bool) {
+	m := slashGrade.FindStringSubmatch(grade)
+	if m == nil || m[4] == "" && m[5] == "" {
+		return 0, false
+	}
+
+	// The second grade inherits the prefix and, for 5.10a/b, the number
+	lo := m[1] + m[2] + m[3]
+	hi := m[1] + m[4] + m[5]
+	if m[4] == "" {
+		hi = m[1] + m[2] + m[5]
+	}
+
+	loVal, hiVal := ParseGrade(lo), ParseGrade(hi)
+	if loVal >= 1000000.0 || hiVal >= 1000000.0 {
+		return 0, false
+	}
+	return (loVal + hiVal) / 2, true
+}
+
+// NormalizeGrade standardizes the casing of a grade so "v5" and "V5", or
+// "5.10A" and "5.10a", are the same grade
+// Font and French grades are left alone since their case tells them apart
+func NormalizeGrade(grade string) string {
+	switch {
+	case len(grade) > 1 && grade[0] == 'v' && strings.ContainsRune("0123456789?", rune(grade[1])):
+		return "V" + grade[1:]
+	case strings.HasPrefix(grade, "5."):
+		return strings.ToLower(grade)
+	}
+	return grade
+}
+
+// ParseGrade extracts numeric value for sorting
+// Sorting order: point grades (900, 1000, ...) < unknown grades (?, ??) < rope grades (5.?, 5.x, French, UIAA, British) < boulder grades (V?, Vx, Font)
+func ParseGrade(grade string) float64 {
+	grade = NormalizeGrade(grade)
+
+	// Handle question marks and unknown grades, keeping V? and 5.? within
+	// their discipline's band
+	if strings.Contains(grade, "?") {
+		switch {
+		case strings.HasPrefix(grade, "V"):
+			return 99000.0 // Sort before all V-grades and Font grades
+		case strings.HasPrefix(grade, "5."):
+			return 20000.0 // Sort before all rope grades
+		}
+		return 10000.0 // Sort after point grades but before rope grades
+	}
+
+	// Handle slash grades (V5/6, 5.10/11) between their two ends
+	if val, ok := parseSlashGrade(grade); ok {
+		return val
+	}
+
+	// Handle British trad grades (HVS 5a, E2) before V-grades since VS and VD start with V
+	if val, ok := parseBritishGrade(grade); ok {
+		// Nudge slightly so British grades sort just after the equivalent YDS grade
+		return val + 20000.0 + 0.03
+	}
+
+	// Handle UIAA grades (VII+, VIII-) before V-grades since both start with V
+	if val, ok := parseUIAAGrade(grade); ok {
+		// Nudge slightly so UIAA grades sort just after the equivalent YDS grade
+		return val + 20000.0 + 0.02
+	}
+
+	// Handle V-grades (boulder grades)
+	if strings.HasPrefix(grade, "V") {
+		g := strings.TrimPrefix(grade, "V")
+		hasPlus := strings.HasSuffix(g, "+")
+		hasMinus := strings.HasSuffix(g, "-")
+		g = strings.TrimSuffix(g, "+")
+		g = strings.TrimSuffix(g, "-")
+
+		val, err := strconv.ParseFloat(g, 64)
+		if err != nil {
+			return 1000000.0 // Sort unknown V-grades last
+		}
+
+		// Add 100000 to separate V-grades from rope grades
+		val += 100000.0
+
+		// Add small amounts for modifiers
+		if hasPlus {
+			val += 0.1
+		} else if hasMinus {
+			val -= 0.1
+		}
+
+		return val
+	}
+
+	// Handle rope grades (5.x format)
+	if strings.HasPrefix(grade, "5.") {
+		g := strings.TrimPrefix(grade, "5.")
+		hasPlus := strings.HasSuffix(g, "+")
+		hasMinus := strings.HasSuffix(g, "-")
+		g = strings.TrimSuffix(g, "+")
+		g = strings.TrimSuffix(g, "-")
+
+		// Letter suffixes add a fractional offset (a=.0, b=.25, c=.5, d=.75)
+		letter := 0.0
+		if n := len(g); n > 0 {
+			if i := strings.IndexByte("abcd", strings.ToLower(g)[n-1]); i >= 0 {
+				letter = float64(i) * 0.25
+				g = g[:n-1]
+			}
+		}
+
+		val, err := strconv.ParseFloat(g, 64)
+		if err != nil {
+			return 10000.0 // Sort unknown rope grades with question marks
+		}
+
+		// Add 20000 to separate rope grades from point grades
+		val += 20000.0 + letter
+
+		// Add small amounts for modifiers
+		if hasPlus {
+			val += 0.1
+		} else if hasMinus {
+			val -= 0.1
+		}
+
+		return val
+	}
+
+	// Handle Font boulder grades (6B+, 7A), placed alongside their V-grade equivalent
+	if val, ok := parseFontGrade(grade); ok {
+		// Nudge slightly so Font grades sort just after the equivalent V-grade
+		return val + 100000.0 + 0.01
+	}
+
+	// Handle French sport grades (6a, 7b+), placed alongside their YDS equivalent
+	if val, ok := parseFrenchGrade(grade); ok {
+		// Nudge slightly so French grades sort just after the equivalent YDS grade
+		return val + 20000.0 + 0.01
+	}
+
+	// Handle point grades (pure numbers like 900, 1000, 1100)
+	val, err := strconv.ParseFloat(grade, 64)
+	if err != nil {
+		return 1000000.0 // Sort unknown grades last
+	}
+
+	return val
+}
+
+// Discipline classifies a grade by the band ParseGrade places it in:
+// "boulder", "rope", "point" or "unknown"
+func Discipline(grade string) string {
+	_, discipline := equivalent(grade)
+	return discipline
+}
+
+// equivalent returns a grade's value within its discipline (a V-grade number
+// for boulder grades, a YDS number for rope grades) along with the discipline
+func equivalent(grade string) (float64, string) {
+	val := ParseGrade(grade)
+	switch {
+	case val >= 1000000.0:
+		return val, "unknown"
+	case val >= 90000.0:
+		return val - 100000.0, "boulder"
+	case val > 10000.0:
+		return val - 20000.0, "rope"
+	case val == 10000.0:
+		return val, "unknown"
+	default:
+		return val, "point"
+	}
+}
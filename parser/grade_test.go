@@ -0,0 +1,166 @@
+package parser
+
+import (
+	"slices"
+	"sort"
+	"testing"
+)
+
+// sortedGrades returns grades sorted by ParseGrade, easiest first
+func sortedGrades(grades []string) []string {
+	sorted := slices.Clone(grades)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return ParseGrade(sorted[i]) < ParseGrade(sorted[j])
+	})
+	return sorted
+}
+
+func TestParseGradeFontAmongVGrades(t *testing.T) {
+	tests := []struct {
+		grades []string
+		want   []string
+	}{
+		{
+			grades: []string{"V5", "6B+", "7A", "V6"},
+			want:   []string{"6B+", "V5", "V6", "7A"},
+		},
+		{
+			grades: []string{"7C+", "V3", "6A", "8A", "V10"},
+			want:   []string{"V3", "6A", "V10", "7C+", "8A"},
+		},
+		{
+			// Below 6A, off the low end of the table
+			grades: []string{"6A", "5C+", "V0", "5A"},
+			want:   []string{"V0", "5A", "5C+", "6A"},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := sortedGrades(tt.grades); !slices.Equal(got, tt.want) {
+			t.Errorf("sorted %v = %v, want %v", tt.grades, got, tt.want)
+		}
+	}
+}
+
+func TestParseGradeUIAA(t *testing.T) {
+	ordered := []string{"IV", "VI-", "VI", "VII", "VII+", "VIII-", "VIII", "IX+", "XI"}
+	for i := 1; i < len(ordered); i++ {
+		if lo, hi := ParseGrade(ordered[i-1]), ParseGrade(ordered[i]); lo >= hi {
+			t.Errorf("ParseGrade(%q) = %v, want below ParseGrade(%q) = %v", ordered[i-1], lo, ordered[i], hi)
+		}
+	}
+
+	tests := []struct {
+		grade string
+		want  string
+	}{
+		{"VII", "rope"},
+		{"VII+", "rope"},
+		{"VIII-", "rope"},
+		{"V", "rope"},
+		{"V5", "boulder"},
+		{"V10", "boulder"},
+	}
+	for _, tt := range tests {
+		if got := Discipline(tt.grade); got != tt.want {
+			t.Errorf("Discipline(%q) = %q, want %q", tt.grade, got, tt.want)
+		}
+	}
+}
+
+func TestParseGradeYDSLetters(t *testing.T) {
+	ordered := []string{
+		"5.9", "5.10-", "5.10a", "5.10a+", "5.10b-", "5.10b", "5.10c", "5.10d", "5.10d+",
+		"5.11a-", "5.11a", "5.11b", "5.11c", "5.11c+", "5.11d", "5.12a",
+	}
+	for i := 1; i < len(ordered); i++ {
+		if lo, hi := ParseGrade(ordered[i-1]), ParseGrade(ordered[i]); lo >= hi {
+			t.Errorf("ParseGrade(%q) = %v, want below ParseGrade(%q) = %v", ordered[i-1], lo, ordered[i], hi)
+		}
+	}
+}
+
+func TestNormalizeGrade(t *testing.T) {
+	tests := []struct {
+		grade string
+		want  string
+	}{
+		{"v5", "V5"},
+		{"V5", "V5"},
+		{"v10+", "V10+"},
+		{"v?", "V?"},
+		{"5.10A", "5.10a"},
+		{"5.10a", "5.10a"},
+		{"5.11D+", "5.11d+"},
+
+		// Font and French grades differ only by case, so they're left alone
+		{"6B+", "6B+"},
+		{"6b+", "6b+"},
+		{"7A", "7A"},
+		{"7a", "7a"},
+
+		// Words starting with v aren't V-grades
+		{"vs", "vs"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeGrade(tt.grade); got != tt.want {
+			t.Errorf("NormalizeGrade(%q) = %q, want %q", tt.grade, got, tt.want)
+		}
+	}
+
+	if ParseGrade("v5") != ParseGrade("V5") || ParseGrade("5.10A") != ParseGrade("5.10a") {
+		t.Errorf("ParseGrade differs by case for V or YDS grades")
+	}
+}
+
+func TestParseGradeQuestionMarks(t *testing.T) {
+	tests := []struct {
+		grade string
+		want  string
+	}{
+		{"V?", "boulder"},
+		{"5.?", "rope"},
+		{"??", "unknown"},
+		{"?", "unknown"},
+	}
+	for _, tt := range tests {
+		if got := Discipline(tt.grade); got != tt.want {
+			t.Errorf("Discipline(%q) = %q, want %q", tt.grade, got, tt.want)
+		}
+	}
+
+	// Each unknown sits at the bottom of its own band
+	grades := []string{"V0", "V?", "5.1", "5.?", "??", "1000"}
+	want := []string{"1000", "??", "5.?", "5.1", "V?", "V0"}
+	if got := sortedGrades(grades); !slices.Equal(got, want) {
+		t.Errorf("sorted %v = %v, want %v", grades, got, want)
+	}
+}
+
+func TestParseGradeSlash(t *testing.T) {
+	tests := []struct {
+		lo, slash, hi string
+	}{
+		{"V5", "V5/6", "V6"},
+		{"V0", "V0/1", "V1"},
+		{"5.10a", "5.10/11", "5.11a"},
+		{"5.10a", "5.10a/b", "5.10b"},
+		{"5.11c", "5.11c/d", "5.11d"},
+	}
+
+	for _, tt := range tests {
+		lo, slash, hi := ParseGrade(tt.lo), ParseGrade(tt.slash), ParseGrade(tt.hi)
+		if !(lo < slash && slash < hi) {
+			t.Errorf("ParseGrade(%q) = %v, want between %q (%v) and %q (%v)", tt.slash, slash, tt.lo, lo, tt.hi, hi)
+		}
+	}
+
+	if got := Discipline("V5/6"); got != "boulder" {
+		t.Errorf("Discipline(%q) = %q, want boulder", "V5/6", got)
+	}
+	if got := Discipline("5.10/11"); got != "rope" {
+		t.Errorf("Discipline(%q) = %q, want rope", "5.10/11", got)
+	}
+}
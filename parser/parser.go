@@ -0,0 +1,549 @@
+// Package parser extracts climbing sends from the YAML or JSON front matter of
+// Hugo content files and orders them by grade.
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Send is a single send parsed from a frontmatter send string
+type Send struct {
+	Color string `json:"color"`
+	Grade string `json:"grade"`
+	Meta  string `json:"meta"`
+	Date  string `json:"date"`
+
+	// Tries and Style are parsed from Meta, e.g. "(3 tries)" or "flash"
+	Tries int    `json:"tries,omitempty"`
+	Style string `json:"style,omitempty"`
+
+	// Project marks a route being worked but not yet sent, written as a
+	// "PROJECT" prefix or a "project" keyword in the meta
+	Project bool `json:"project,omitempty"`
+
+	// FA marks a first ascent, written as an "FA" token in the meta
+	FA bool `json:"fa,omitempty"`
+
+	// Danger is the protection rating following the grade: "PG13", "R" or "X"
+	Danger string `json:"danger,omitempty"`
+
+	// Location is where the send happened, from the front matter location field
+	Location string `json:"location,omitempty"`
+}
+
+// String formats the send as color, grade and meta separated by single spaces
+// Meta starting with punctuation such as ", flash" follows the grade directly
+func (s Send) String() string {
+	out := s.Grade
+	if s.Color != "" {
+		out = s.Color + " " + out
+	}
+	if s.Project && !projectPattern.MatchString(s.Meta) {
+		out = "PROJECT " + out
+	}
+	if meta := strings.TrimSpace(s.Meta); meta != "" {
+		if !strings.ContainsAny(meta[:1], ",;:.!") {
+			out += " "
+		}
+		out += meta
+	}
+	return out
+}
+
+// Errors returned by ExtractFrontmatter, for checking with errors.Is
+var (
+	// ErrNoFrontmatter means the content doesn't open with front matter
+	ErrNoFrontmatter = errors.New("no front matter")
+
+	// ErrMalformedFrontmatter means the front matter isn't valid YAML or JSON,
+	// or doesn't have the expected fields
+	ErrMalformedFrontmatter = errors.New("malformed front matter")
+)
+
+// Frontmatter holds the front matter fields used by sends
+type Frontmatter struct {
+	Date     string      `yaml:"date" json:"date"`
+	Location string      `yaml:"location" json:"location"`
+	Sends    []SendEntry `yaml:"sends" json:"sends"`
+}
+
+// Options controls which front matter fields are read
+type Options struct {
+	// DateFields lists the keys tried in order for the send date (default "date")
+	DateFields []string
+
+	// SendsFields lists the keys tried in order for the sends list (default
+	// "sends")
+	SendsFields []string
+
+	// Pattern parses send strings in place of the default pattern; see
+	// CompileSendPattern
+	Pattern *regexp.Regexp
+
+	// Body also reads sends from bullet list items in the document body
+	Body bool
+}
+
+// ExtractFrontmatterFile reads the YAML front matter from the file at path
+// Files ending in .gz are decompressed transparently
+func ExtractFrontmatterFile(path string, opts Options) (*Frontmatter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(strings.ToLower(path), ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	return ExtractFrontmatter(r, opts)
+}
+
+// ExtractFrontmatter reads the front matter from r, either YAML between ---
+// delimiters or a JSON object opening with {
+// Content that doesn't open with front matter returns ErrNoFrontmatter, unless
+// opts.Body finds sends in it; front matter that can't be parsed returns an
+// error wrapping ErrMalformedFrontmatter
+func ExtractFrontmatter(r io.Reader, opts Options) (*Frontmatter, error) {
+	scanner := bufio.NewScanner(r)
+	var frontmatterLines []string
+	format := ""
+	depth := 0
+
+	// Lines before the front matter content, for reporting error positions
+	offset := 0
+
+	for scanner.Scan() {
+		if format == "" {
+			offset++
+		}
+
+		// Drop the carriage return from CRLF line endings
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if format == "" {
+			// Frontmatter must open on the first non-empty line, ignoring a BOM
+			line = strings.TrimPrefix(line, "\uFEFF")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+
+			switch {
+			case line == "---":
+				format = "yaml"
+				continue
+			case strings.HasPrefix(line, "{"):
+				format = "json"
+				offset--
+			default:
+				// No frontmatter; don't go looking for one mid-document
+				if opts.Body {
+					entries, err := scanBody(line, scanner)
+					if err != nil {
+						return nil, err
+					}
+					if len(entries) > 0 {
+						return &Frontmatter{Sends: entries}, nil
+					}
+				}
+				return nil, ErrNoFrontmatter
+			}
+		}
+
+		if format == "yaml" {
+			// Stop at the first closing delimiter, ignoring any later --- in the body
+			if line == "---" {
+				break
+			}
+			frontmatterLines = append(frontmatterLines, line)
+			continue
+		}
+
+		// JSON frontmatter ends at the brace matching the opening one
+		frontmatterLines = append(frontmatterLines, line)
+		if depth = jsonDepth(line, depth); depth <= 0 {
+			break
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if format == "" {
+		return nil, ErrNoFrontmatter
+	}
+
+	content := []byte(strings.Join(frontmatterLines, "\n"))
+	var fm *Frontmatter
+	var err error
+	if format == "json" {
+		fm, err = parseJSONFrontmatter(content, opts)
+	} else {
+		fm, err = parseYAMLFrontmatter(content, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMalformedFrontmatter, fileLineError(err, content, offset))
+	}
+
+	// Body sends follow the front matter ones, sharing its date
+	if opts.Body && scanner.Scan() {
+		entries, err := scanBody(scanner.Text(), scanner)
+		if err != nil {
+			return nil, err
+		}
+		fm.Sends = append(fm.Sends, entries...)
+	}
+	return fm, nil
+}
+
+// bulletPattern matches a markdown bullet list item, capturing its text
+var bulletPattern = regexp.MustCompile(`^\s*[-*]\s+(.+)$`)
+
+// scanBody collects the text of bullet list items from line and the lines
+// remaining in scanner
+func scanBody(line string, scanner *bufio.Scanner) ([]SendEntry, error) {
+	var entries []SendEntry
+	for {
+		if m := bulletPattern.FindStringSubmatch(strings.TrimSuffix(line, "\r")); m != nil {
+			entries = append(entries, SendEntry{Text: strings.TrimSpace(m[1])})
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line = scanner.Text()
+	}
+	return entries, scanner.Err()
+}
+
+// errorLine matches the line number yaml.v3 includes in its error messages
+var errorLine = regexp.MustCompile(`\bline (\d+)`)
+
+// fileLineError rewrites the line numbers in a front matter parse error so they
+// count from the top of the file rather than the start of the front matter
+func fileLineError(err error, content []byte, offset int) error {
+	// JSON errors carry a byte offset instead of a line number
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line := bytes.Count(content[:min(int(syntaxErr.Offset), len(content))], []byte("\n")) + 1
+		return fmt.Errorf("line %d: %w", line+offset, err)
+	}
+
+	msg := errorLine.ReplaceAllStringFunc(err.Error(), func(m string) string {
+		n, _ := strconv.Atoi(errorLine.FindStringSubmatch(m)[1])
+		return "line " + strconv.Itoa(n+offset)
+	})
+	return errors.New(msg)
+}
+
+// parseYAMLFrontmatter parses YAML front matter content
+func parseYAMLFrontmatter(content []byte, opts Options) (*Frontmatter, error) {
+	var fm Frontmatter
+	if err := yaml.Unmarshal(content, &fm); err != nil {
+		return nil, err
+	}
+
+	if len(opts.DateFields) == 0 && len(opts.SendsFields) == 0 {
+		return &fm, nil
+	}
+	var fields map[string]yaml.Node
+	if err := yaml.Unmarshal(content, &fields); err != nil {
+		return nil, err
+	}
+
+	// Read the date from the first configured field that is set
+	if len(opts.DateFields) > 0 {
+		fm.Date = ""
+		for _, name := range opts.DateFields {
+			if node, ok := fields[name]; ok && node.Kind == yaml.ScalarNode && node.Value != "" {
+				fm.Date = node.Value
+				break
+			}
+		}
+	}
+
+	// Read the sends from the first configured field that is present
+	if len(opts.SendsFields) > 0 {
+		fm.Sends = nil
+		for _, name := range opts.SendsFields {
+			if node, ok := fields[name]; ok {
+				if err := node.Decode(&fm.Sends); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+
+	return &fm, nil
+}
+
+// parseJSONFrontmatter parses JSON front matter content
+func parseJSONFrontmatter(content []byte, opts Options) (*Frontmatter, error) {
+	var fm Frontmatter
+	if err := json.Unmarshal(content, &fm); err != nil {
+		return nil, err
+	}
+
+	if len(opts.DateFields) == 0 && len(opts.SendsFields) == 0 {
+		return &fm, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(content, &fields); err != nil {
+		return nil, err
+	}
+
+	// Read the date from the first configured field that is set
+	if len(opts.DateFields) > 0 {
+		fm.Date = ""
+		for _, name := range opts.DateFields {
+			var value string
+			if json.Unmarshal(fields[name], &value) == nil && value != "" {
+				fm.Date = value
+				break
+			}
+		}
+	}
+
+	// Read the sends from the first configured field that is present
+	if len(opts.SendsFields) > 0 {
+		fm.Sends = nil
+		for _, name := range opts.SendsFields {
+			if raw, ok := fields[name]; ok {
+				if err := json.Unmarshal(raw, &fm.Sends); err != nil {
+					return nil, err
+				}
+				break
+			}
+		}
+	}
+
+	return &fm, nil
+}
+
+// jsonDepth returns the brace depth after line, starting from depth and
+// ignoring braces inside strings
+func jsonDepth(line string, depth int) int {
+	inString, escaped := false, false
+	for _, c := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case !inString && c == '{':
+			depth++
+		case !inString && c == '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// sendPattern matches the bash scripts, with the color widened to any
+// letters so names like "café" or "Müller" parse
+var sendPattern = regexp.MustCompile(`(?P<color>[\p{L}\p{M}\p{N}_\s']*?\s?)(?P<grade>[Vv]?[\d.+?-]+[a-dA-D]?\+?(?:/(?:\d+[a-dA-D]?|[a-dA-D])[+-]?)?|(?:XI{0,2}|IX|VI{0,3}|IV|I{1,3})\b[+-]?|(?:M|D|HD|VD|HVD|MS|S|HS|MVS|VS|HVS|E\d{1,2})\b(?:\s[4-7][abc]\b)?)(?P<meta>\s?.*)`)
+
+// triesPattern matches an attempt count in meta like "(3 tries)" or "(1 try)"
+var triesPattern = regexp.MustCompile(`(?i)\((\d+)\s*tr(?:y|ies)\)`)
+
+// stylePattern matches an ascent style keyword in meta
+var stylePattern = regexp.MustCompile(`(?i)\b(flash|onsight)\b`)
+
+// projectPrefix matches the marker a project send string opens with
+var projectPrefix = regexp.MustCompile(`^\s*PROJECT\b:?\s*`)
+
+// projectPattern matches a project keyword in meta
+var projectPattern = regexp.MustCompile(`(?i)\bproject\b`)
+
+// dangerPattern matches a protection rating opening the meta, like "R" in
+// "5.10 R" or "PG-13" in "5.9 PG-13 loose rock"
+var dangerPattern = regexp.MustCompile(`^(PG-?13|R|X)\b`)
+
+// DangerLevels lists the protection ratings from safest to most dangerous
+var DangerLevels = []string{"PG13", "R", "X"}
+
+// parseDanger returns the protection rating opening meta, if any
+func parseDanger(meta string) string {
+	m := dangerPattern.FindStringSubmatch(meta)
+	if m == nil {
+		return ""
+	}
+	return strings.ReplaceAll(m[1], "-", "")
+}
+
+// faPattern matches a first ascent token in meta
+var faPattern = regexp.MustCompile(`\bFA\b`)
+
+// parseMeta extracts the attempt count and ascent style from a send's meta
+func parseMeta(meta string) (tries int, style string) {
+	if m := triesPattern.FindStringSubmatch(meta); m != nil {
+		tries, _ = strconv.Atoi(m[1])
+	}
+	if m := stylePattern.FindStringSubmatch(meta); m != nil {
+		style = strings.ToLower(m[1])
+	}
+	return tries, style
+}
+
+// CompileSendPattern compiles a custom send pattern for Options.Pattern
+// The pattern must have named color, grade and meta groups
+func CompileSendPattern(expr string) (*regexp.Regexp, error) {
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range []string{"color", "grade", "meta"} {
+		if pattern.SubexpIndex(group) < 0 {
+			return nil, fmt.Errorf("missing named group (?P<%s>...)", group)
+		}
+	}
+	return pattern, nil
+}
+
+// ParseSend parses a single send string like "blue V5 flash" with the send regex
+// A leading "PROJECT" marker is removed and recorded in Project
+// The returned send has no date; ok is false if the string doesn't match
+func ParseSend(s string) (send Send, ok bool) {
+	return parseSendPattern(sendPattern, s)
+}
+
+// parseSendPattern parses a send string with pattern, reading the color,
+// grade and meta from its named groups
+func parseSendPattern(pattern *regexp.Regexp, s string) (send Send, ok bool) {
+	project := projectPrefix.MatchString(s)
+	s = projectPrefix.ReplaceAllString(s, "")
+
+	// Go's \b only knows ASCII letters, so a grade like "M" can match the start
+	// of "Müller"; when a grade runs into a letter, keep that word in the color
+	prefix := ""
+	matches := pattern.FindStringSubmatchIndex(s)
+	for matches != nil {
+		end := matches[2*pattern.SubexpIndex("grade")+1]
+		if end < 0 {
+			// A custom pattern's optional grade group didn't match
+			break
+		}
+		last, _ := utf8.DecodeLastRuneInString(s[:end])
+		next, size := utf8.DecodeRuneInString(s[end:])
+		if end == len(s) || !unicode.IsLetter(last) || !unicode.IsLetter(next) {
+			break
+		}
+		prefix += s[:end+size]
+		s = s[end+size:]
+		matches = pattern.FindStringSubmatchIndex(s)
+	}
+	if matches == nil {
+		return Send{}, false
+	}
+	group := func(name string) string {
+		i := pattern.SubexpIndex(name)
+		if matches[2*i] < 0 {
+			return ""
+		}
+		return s[matches[2*i]:matches[2*i+1]]
+	}
+
+	send = Send{
+		// Collapse runs of whitespace in the color so "light  blue" matches "light blue"
+		Color: strings.Join(strings.Fields(prefix+group("color")), " "),
+		Grade: NormalizeGrade(group("grade")),
+		Meta:  strings.TrimSpace(group("meta")),
+	}
+	send.Tries, send.Style = parseMeta(send.Meta)
+	send.Project = project || projectPattern.MatchString(send.Meta)
+	send.FA = faPattern.MatchString(send.Meta)
+	send.Danger = parseDanger(send.Meta)
+
+	return send, true
+}
+
+// dateLayouts are the timestamp formats NormalizeDate shortens to a date,
+// starting with Hugo's default RFC 3339 timestamps
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// NormalizeDate shortens a timestamp like "2024-05-01T18:30:00Z" to its date,
+// "2024-05-01", in the timestamp's own time zone
+// Anything else, including plain dates, is returned unchanged
+func NormalizeDate(date string) string {
+	date = strings.TrimSpace(date)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return date
+}
+
+// ParseSends parses each string send in the frontmatter with opts.Pattern,
+// or ParseSend if it isn't set, and
+// takes mapping sends as given, dating and locating those without a date or
+// location of their own, with timestamps shortened by NormalizeDate
+// Strings that don't match and mappings without a grade are returned
+// separately as unmatched; skipped and commented entries are left out of both
+func ParseSends(fm *Frontmatter, opts Options) (sends []Send, unmatched []string) {
+	pattern := sendPattern
+	if opts.Pattern != nil {
+		pattern = opts.Pattern
+	}
+
+	for _, entry := range fm.Sends {
+		if entry.Skipped != "" || entry.Commented() {
+			continue
+		}
+
+		var send Send
+		if entry.Send != nil {
+			if entry.Send.Grade == "" {
+				unmatched = append(unmatched, entry.String())
+				continue
+			}
+			send = *entry.Send
+		} else {
+			var ok bool
+			if send, ok = parseSendPattern(pattern, entry.Text); !ok {
+				unmatched = append(unmatched, entry.Text)
+				continue
+			}
+		}
+		if send.Date == "" {
+			send.Date = fm.Date
+		}
+		send.Date = NormalizeDate(send.Date)
+		if send.Location == "" {
+			send.Location = fm.Location
+		}
+		sends = append(sends, send)
+	}
+	return sends, unmatched
+}
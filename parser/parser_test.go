@@ -0,0 +1,191 @@
+package parser
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseSendsSkipsCommented(t *testing.T) {
+	fm := &Frontmatter{
+		Date: "2024-05-01",
+		Sends: []SendEntry{
+			{Text: "red V3"},
+			{Text: "# blue V5"},
+			{Text: "  #green V1 not yet"},
+			{Text: "pink V2 #2 on the wall"},
+		},
+	}
+
+	sends, unmatched := ParseSends(fm, Options{})
+	var got []string
+	for _, send := range sends {
+		got = append(got, send.String())
+	}
+
+	want := []string{"red V3", "pink V2 #2 on the wall"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseSends sends = %q, want %q", got, want)
+	}
+	if len(unmatched) != 0 {
+		t.Errorf("ParseSends unmatched = %q, want none", unmatched)
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		// Plain dates and anything unparseable pass through
+		{"2024-05-01", "2024-05-01"},
+		{"", ""},
+		{"May 1", "May 1"},
+
+		// RFC 3339, as Hugo writes by default
+		{"2024-05-01T18:30:00Z", "2024-05-01"},
+		{"2024-05-01T18:30:00.123Z", "2024-05-01"},
+		{"2024-05-01T18:30:00", "2024-05-01"},
+		{"2024-05-01T18:30", "2024-05-01"},
+
+		// Zone offsets keep the date in the timestamp's own zone
+		{"2024-05-02T23:30:00-07:00", "2024-05-02"},
+		{"2024-05-02T00:30:00+02:00", "2024-05-02"},
+
+		// Space-separated timestamps
+		{"2024-05-03 08:00:00", "2024-05-03"},
+		{"2024-05-03 08:00", "2024-05-03"},
+		{"2024-05-03 23:59:59 -0700", "2024-05-03"},
+		{"2024-05-03 23:59:59+02:00", "2024-05-03"},
+		{" 2024-05-03T08:00:00Z ", "2024-05-03"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeDate(tt.date); got != tt.want {
+			t.Errorf("NormalizeDate(%q) = %q, want %q", tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestParseSendsTimestampedDates(t *testing.T) {
+	fm := &Frontmatter{
+		Date: "2024-05-01T18:30:00Z",
+		Sends: []SendEntry{
+			{Text: "red V3"},
+			{Send: &Send{Grade: "V4", Date: "2024-05-03 08:00:00"}},
+		},
+	}
+
+	sends, _ := ParseSends(fm, Options{})
+	var got []string
+	for _, send := range sends {
+		got = append(got, send.Date)
+	}
+
+	want := []string{"2024-05-01", "2024-05-03"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseSends dates = %q, want %q", got, want)
+	}
+}
+
+func TestParseSendWhitespace(t *testing.T) {
+	tests := []struct {
+		s     string
+		color string
+		grade string
+		meta  string
+	}{
+		{"  red V4", "red", "V4", ""},
+		{"blue  V5 flash", "blue", "V5", "flash"},
+		{"blue V5   flash  ", "blue", "V5", "flash"},
+		{"light   blue V3", "light blue", "V3", ""},
+		{"\tred\tV4\tflash", "red", "V4", "flash"},
+		{"light\t\tblue \t V3 \t(3 tries)", "light blue", "V3", "(3 tries)"},
+	}
+
+	for _, tt := range tests {
+		send, ok := ParseSend(tt.s)
+		if !ok {
+			t.Errorf("ParseSend(%q) failed to parse", tt.s)
+			continue
+		}
+		if send.Color != tt.color || send.Grade != tt.grade || send.Meta != tt.meta {
+			t.Errorf("ParseSend(%q) = %q, %q, %q; want %q, %q, %q",
+				tt.s, send.Color, send.Grade, send.Meta, tt.color, tt.grade, tt.meta)
+		}
+	}
+}
+
+func TestExtractFrontmatterCRLF(t *testing.T) {
+	content := "---\r\ndate: 2024-05-01\r\nsends:\r\n  - red V3\r\n  - blue V5 flash\r\n---\r\nbody\r\n"
+
+	fm, err := ExtractFrontmatter(strings.NewReader(content), Options{})
+	if err != nil {
+		t.Fatalf("ExtractFrontmatter: %v", err)
+	}
+	if fm.Date != "2024-05-01" {
+		t.Errorf("Date = %q, want %q", fm.Date, "2024-05-01")
+	}
+
+	sends, unmatched := ParseSends(fm, Options{})
+	var got []string
+	for _, send := range sends {
+		got = append(got, send.String())
+	}
+	want := []string{"red V3", "blue V5 flash"}
+	if !slices.Equal(got, want) || len(unmatched) != 0 {
+		t.Errorf("ParseSends = %q, unmatched %q; want %q", got, unmatched, want)
+	}
+}
+
+func TestParseSendUnicodeColors(t *testing.T) {
+	tests := []struct {
+		s     string
+		color string
+		grade string
+		meta  string
+	}{
+		{"café V3", "café", "V3", ""},
+		{"crème brûlée 6a+ flash", "crème brûlée", "6a+", "flash"},
+		{"Ñandú V5", "Ñandú", "V5", ""},
+
+		// "M" is a British grade, and Go's ASCII \b lets it match the start
+		// of "Müller", so the word has to be carried into the color
+		{"Müller M", "Müller", "M", ""},
+		{"Müller V2", "Müller", "V2", ""},
+		{"Müller Mädchen VS 4c onsight", "Müller Mädchen", "VS 4c", "onsight"},
+	}
+
+	for _, tt := range tests {
+		send, ok := ParseSend(tt.s)
+		if !ok {
+			t.Errorf("ParseSend(%q) failed to parse", tt.s)
+			continue
+		}
+		if send.Color != tt.color || send.Grade != tt.grade || send.Meta != tt.meta {
+			t.Errorf("ParseSend(%q) = %q, %q, %q; want %q, %q, %q",
+				tt.s, send.Color, send.Grade, send.Meta, tt.color, tt.grade, tt.meta)
+		}
+	}
+}
+
+func TestParseSendsOptionalGradePattern(t *testing.T) {
+	pattern, err := CompileSendPattern(`(?P<color>[a-z]*)\s*(?P<grade>V\d+)?(?P<meta>.*)`)
+	if err != nil {
+		t.Fatalf("CompileSendPattern: %v", err)
+	}
+	fm := &Frontmatter{
+		Date:  "2024-05-01",
+		Sends: []SendEntry{{Text: "red"}, {Text: "blue V5 flash"}},
+	}
+
+	sends, _ := ParseSends(fm, Options{Pattern: pattern})
+	var got []string
+	for _, send := range sends {
+		got = append(got, send.Color+"|"+send.Grade+"|"+send.Meta)
+	}
+	want := []string{"red||", "blue|V5|flash"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseSends = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// ContentFiles are the file name patterns ParseSite looks for
+var ContentFiles = []string{"index.md"}
+
+// IsContentFile reports whether name matches any of the filename glob
+// patterns, ignoring case and an optional .gz suffix
+func IsContentFile(name string, patterns []string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".gz")
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(strings.ToLower(pattern), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FileSends is the outcome of parsing a single content file
+type FileSends struct {
+	Sends     []Send
+	Unmatched []string
+
+	// Skipped holds why each entry that isn't a send was skipped
+	Skipped []string
+
+	// NoFrontmatter is set for files without front matter, which have no sends
+	NoFrontmatter bool
+}
+
+// ParseFile extracts the sends from the front matter of the content file at
+// path, which may be gzipped
+// A file without front matter isn't an error; it simply has no sends
+func ParseFile(path string, opts Options) (FileSends, error) {
+	fm, err := ExtractFrontmatterFile(path, opts)
+	if errors.Is(err, ErrNoFrontmatter) {
+		return FileSends{NoFrontmatter: true}, nil
+	}
+	if err != nil {
+		return FileSends{}, err
+	}
+
+	var result FileSends
+	result.Sends, result.Unmatched = ParseSends(fm, opts)
+	for _, entry := range fm.Sends {
+		if entry.Skipped != "" {
+			result.Skipped = append(result.Skipped, entry.Skipped)
+		}
+	}
+	return result, nil
+}
+
+// ParseSite walks the content/<contentType> directory of the Hugo site at
+// root and returns the sends from the front matter of every index.md (or
+// index.md.gz) file, unsorted
+// Errors walking the directory are returned; files whose front matter can't
+// be parsed are skipped
+func ParseSite(root, contentType string) ([]Send, error) {
+	var sends []Send
+
+	contentPath := filepath.Join(root, "content", contentType)
+	err := filepath.WalkDir(contentPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !IsContentFile(d.Name(), ContentFiles) {
+			return nil
+		}
+
+		result, err := ParseFile(path, Options{})
+		if err != nil {
+			return nil
+		}
+		sends = append(sends, result.Sends...)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sends, nil
+}
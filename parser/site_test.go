@@ -0,0 +1,58 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseSite(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"a/index.md":  "---\ndate: 2024-05-01\nsends:\n  - blue V2 flash\n---\n",
+		"b/INDEX.MD":  "---\ndate: 2024-05-02\nsends:\n  - red V4\n---\n",
+		"c/index.md":  "No front matter here\n",
+		"d/notes.md":  "---\ndate: 2024-05-03\nsends:\n  - green V6\n---\n",
+		"e/index.md":  "---\ndate: [unclosed\n---\n",
+		"f/_index.md": "---\nsends:\n  - yellow V8\n---\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, "content", "posts", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sends, err := ParseSite(root, "posts")
+	if err != nil {
+		t.Fatalf("ParseSite: %v", err)
+	}
+	var got []string
+	for _, send := range sends {
+		got = append(got, send.String())
+	}
+	want := []string{"blue V2 flash", "red V4"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseSite = %q, want %q", got, want)
+	}
+
+	if _, err := ParseSite(root, "missing"); err == nil {
+		t.Error("ParseSite of a missing content type: want error")
+	}
+}
+
+func TestParseFileNoFrontmatter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.md")
+	if err := os.WriteFile(path, []byte("Just prose\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ParseFile(path, Options{})
+	if err != nil || !result.NoFrontmatter || len(result.Sends) != 0 {
+		t.Errorf("ParseFile = %+v, %v; want no front matter and no error", result, err)
+	}
+}
@@ -0,0 +1,702 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sends/parser"
+)
+
+// uniqueDates returns the unique dates of sends with the given grade, sorted chronologically
+func uniqueDates(sends []parser.Send, grade string) []string {
+	dateMap := make(map[string]bool)
+	dates := []string{}
+
+	// Collect unique dates for the specified grade
+	for _, send := range sends {
+		if send.Grade == grade && send.Date != "" {
+			if !dateMap[send.Date] {
+				dateMap[send.Date] = true
+				dates = append(dates, send.Date)
+			}
+		}
+	}
+
+	// Sort dates chronologically
+	sort.Slice(dates, func(i, j int) bool {
+		return dateBefore(dates[i], dates[j])
+	})
+
+	return dates
+}
+
+// GradeCount is the number of sends at a single grade
+type GradeCount struct {
+	Grade string `json:"grade"`
+	Count int    `json:"count"`
+
+	// Rank is the grade's position by count, set by rankGrades
+	Rank int `json:"rank,omitempty"`
+}
+
+// countGrades groups sends by grade, preserving the order grades are first seen
+func countGrades(sends []parser.Send) []GradeCount {
+	index := make(map[string]int)
+	counts := []GradeCount{}
+
+	for _, send := range sends {
+		i, seen := index[send.Grade]
+		if !seen {
+			i = len(counts)
+			index[send.Grade] = i
+			counts = append(counts, GradeCount{Grade: send.Grade})
+		}
+		counts[i].Count++
+	}
+
+	return counts
+}
+
+// gradeChange is the difference in sends at a grade between two date ranges
+type gradeChange struct {
+	Grade  string `json:"grade"`
+	Before int    `json:"before"`
+	After  int    `json:"after"`
+	Change int    `json:"change"`
+}
+
+// diffGrades counts the sends at each grade in the before and after ranges,
+// returning the grades whose count changed, in grade order
+func diffGrades(sends []parser.Send, before, after dateRange) []gradeChange {
+	index := make(map[string]int)
+	changes := []gradeChange{}
+
+	for _, send := range sends {
+		inBefore := inDateRange(send.Date, before.since, before.until)
+		inAfter := inDateRange(send.Date, after.since, after.until)
+		if !inBefore && !inAfter {
+			continue
+		}
+
+		i, seen := index[send.Grade]
+		if !seen {
+			i = len(changes)
+			index[send.Grade] = i
+			changes = append(changes, gradeChange{Grade: send.Grade})
+		}
+		if inBefore {
+			changes[i].Before++
+		}
+		if inAfter {
+			changes[i].After++
+		}
+	}
+
+	changed := changes[:0]
+	for _, c := range changes {
+		if c.Change = c.After - c.Before; c.Change != 0 {
+			changed = append(changed, c)
+		}
+	}
+	sort.SliceStable(changed, func(i, j int) bool {
+		return gradeValue(changed[i].Grade) < gradeValue(changed[j].Grade)
+	})
+	return changed
+}
+
+// sortedCounts counts sends per grade, converted to system for display, and
+// sorts the counts by key: "grade" orders them like list mode, easiest first,
+// and "count" puts the most sent grades first, keeping grade order for ties
+// Grades are ordered before converting, since converted labels like "V0~"
+// don't sort as the grade they came from
+func sortedCounts(sends []parser.Send, system, key string, reverse bool) []GradeCount {
+	sorted := slices.Clone(sends)
+	sortSends(sorted, "grade", reverse && key == "grade")
+	counts := countGrades(convertSends(sorted, system))
+	if key == "count" {
+		sort.SliceStable(counts, func(i, j int) bool {
+			return counts[i].Count > counts[j].Count
+		})
+		if reverse {
+			slices.Reverse(counts)
+		}
+	}
+	return counts
+}
+
+// rankGrades orders counts from most to fewest sends, keeping grade order for
+// ties, and ranks them competition style: tied grades share a rank and the
+// next grade skips ahead (1, 2, 2, 4)
+func rankGrades(counts []GradeCount) {
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+	for i := range counts {
+		counts[i].Rank = i + 1
+		if i > 0 && counts[i].Count == counts[i-1].Count {
+			counts[i].Rank = counts[i-1].Rank
+		}
+	}
+}
+
+// labelCount is the number of sends sharing a label such as a color
+type labelCount struct {
+	Label string
+	Count int
+}
+
+// countBy groups sends by the label returned by key, preserving the order labels are first seen
+func countBy(sends []parser.Send, key func(parser.Send) string) []labelCount {
+	index := make(map[string]int)
+	counts := []labelCount{}
+
+	for _, send := range sends {
+		label := key(send)
+		i, seen := index[label]
+		if !seen {
+			i = len(counts)
+			index[label] = i
+			counts = append(counts, labelCount{Label: label})
+		}
+		counts[i].Count++
+	}
+
+	return counts
+}
+
+// gradeTries is the mean number of tries for the sends of a grade that
+// recorded an attempt count
+type gradeTries struct {
+	Grade   string  `json:"grade"`
+	Average float64 `json:"average"`
+	Sends   int     `json:"sends"`
+}
+
+// averageTries returns the mean tries per grade, ordered by grade, and the
+// number of sends left out for not recording their tries
+func averageTries(sends []parser.Send) (averages []gradeTries, untracked int) {
+	index := make(map[string]int)
+	totals := []int{}
+	averages = []gradeTries{}
+
+	for _, send := range sends {
+		if send.Tries == 0 {
+			untracked++
+			continue
+		}
+		i, seen := index[send.Grade]
+		if !seen {
+			i = len(averages)
+			index[send.Grade] = i
+			averages = append(averages, gradeTries{Grade: send.Grade})
+			totals = append(totals, 0)
+		}
+		averages[i].Sends++
+		totals[i] += send.Tries
+	}
+
+	for i := range averages {
+		averages[i].Average = float64(totals[i]) / float64(averages[i].Sends)
+	}
+	sort.SliceStable(averages, func(i, j int) bool {
+		return gradeValue(averages[i].Grade) < gradeValue(averages[j].Grade)
+	})
+	return averages, untracked
+}
+
+// datedGrade is a grade reached by a date
+type datedGrade struct {
+	Date  string `json:"date"`
+	Grade string `json:"grade"`
+}
+
+// progression returns the hardest grade sent up to and including each date
+// with sends, chronologically; undated sends are ignored
+func progression(sends []parser.Send) []datedGrade {
+	var dated []parser.Send
+	for _, send := range sends {
+		if _, err := time.Parse(dateLayout, send.Date); err == nil {
+			dated = append(dated, send)
+		}
+	}
+	sort.SliceStable(dated, func(i, j int) bool {
+		return dateBefore(dated[i].Date, dated[j].Date)
+	})
+
+	rows := []datedGrade{}
+	best, bestGrade := "", 0.0
+	for i, send := range dated {
+		if g := gradeValue(send.Grade); best == "" || g > bestGrade {
+			best, bestGrade = send.Grade, g
+		}
+
+		// One row per date, once all of its sends are counted
+		if i == len(dated)-1 || dated[i+1].Date != send.Date {
+			rows = append(rows, datedGrade{Date: send.Date, Grade: best})
+		}
+	}
+	return rows
+}
+
+// writeSpan outputs a span of days in the given format
+func writeSpan(w io.Writer, format string, span dateSpan) {
+	switch format {
+	case "json":
+		writeJSON(w, span)
+	case "text":
+		if span.Days == 0 && span.Start == "" {
+			fmt.Fprintln(w, "0 days")
+			return
+		}
+		unit := "days"
+		if span.Days == 1 {
+			unit = "day"
+		}
+		fmt.Fprintf(w, "%d %s (%s to %s)\n", span.Days, unit, span.Start, span.End)
+	default:
+		writeRecords(w, format, [][]string{
+			{"days", "start", "end"},
+			{strconv.Itoa(span.Days), span.Start, span.End},
+		})
+	}
+}
+
+// longestPlateau returns the longest span between increases of the hardest
+// grade sent, counting the current span up to the latest send
+// Days is the number of days from the start of the span to its end
+func longestPlateau(sends []parser.Send) dateSpan {
+	rows := progression(sends)
+
+	var best dateSpan
+	start := 0
+	for i := 1; i <= len(rows); i++ {
+		if i < len(rows) && rows[i].Grade == rows[start].Grade {
+			continue
+		}
+
+		// The span runs until the next increase or, for the last, the latest send
+		end := rows[len(rows)-1]
+		if i < len(rows) {
+			end = rows[i]
+		}
+		from, _ := time.Parse(dateLayout, rows[start].Date)
+		to, _ := time.Parse(dateLayout, end.Date)
+		if days := int(to.Sub(from).Hours() / 24); days > best.Days {
+			best = dateSpan{Days: days, Start: rows[start].Date, End: end.Date}
+		}
+		start = i
+	}
+	return best
+}
+
+// gradePercentile is the grade at a percentile of sends
+type gradePercentile struct {
+	Percentile int    `json:"percentile"`
+	Grade      string `json:"grade"`
+}
+
+// percentiles returns the grade of the send at each percentile, using the
+// nearest-rank method on grades ordered by gradeValue
+func percentiles(sends []parser.Send, ps []int) []gradePercentile {
+	rows := []gradePercentile{}
+	if len(sends) == 0 {
+		return rows
+	}
+
+	ordered := slices.Clone(sends)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return gradeValue(ordered[i].Grade) < gradeValue(ordered[j].Grade)
+	})
+
+	for _, p := range ps {
+		rank := int(math.Ceil(float64(p) / 100 * float64(len(ordered))))
+		rows = append(rows, gradePercentile{Percentile: p, Grade: ordered[max(rank, 1)-1].Grade})
+	}
+	return rows
+}
+
+// gradeFirst is the earliest date a grade was sent
+type gradeFirst struct {
+	Grade string `json:"grade"`
+	Date  string `json:"date"`
+}
+
+// firstSends returns the earliest dated send of each grade, ordered by grade
+// Grades with no dated sends are left out
+func firstSends(sends []parser.Send) []gradeFirst {
+	index := make(map[string]int)
+	firsts := []gradeFirst{}
+
+	for _, send := range sends {
+		if send.Date == "" {
+			continue
+		}
+		i, seen := index[send.Grade]
+		if !seen {
+			index[send.Grade] = len(firsts)
+			firsts = append(firsts, gradeFirst{Grade: send.Grade, Date: send.Date})
+			continue
+		}
+		if dateBefore(send.Date, firsts[i].Date) {
+			firsts[i].Date = send.Date
+		}
+	}
+
+	sort.SliceStable(firsts, func(i, j int) bool {
+		return gradeValue(firsts[i].Grade) < gradeValue(firsts[j].Grade)
+	})
+	return firsts
+}
+
+// sendPoints returns the score a send of grade earns, from --points if it
+// lists the grade, otherwise the built-in table
+func sendPoints(grade string) float64 {
+	if p, ok := gradePoints[grade]; ok {
+		return p
+	}
+	return parser.Points(grade)
+}
+
+// dayScore is the summed score of one day's sends
+type dayScore struct {
+	Date  string  `json:"date"`
+	Score float64 `json:"score"`
+}
+
+// scoreSends returns the total score of the sends and the score of each
+// dated day, chronologically
+func scoreSends(sends []parser.Send) (total float64, days []dayScore) {
+	index := make(map[string]int)
+	days = []dayScore{}
+	for _, send := range sends {
+		points := sendPoints(send.Grade)
+		total += points
+		if send.Date == "" {
+			continue
+		}
+		i, seen := index[send.Date]
+		if !seen {
+			i = len(days)
+			index[send.Date] = i
+			days = append(days, dayScore{Date: send.Date})
+		}
+		days[i].Score += points
+	}
+
+	sort.SliceStable(days, func(i, j int) bool {
+		return dateBefore(days[i].Date, days[j].Date)
+	})
+	return total, days
+}
+
+// formatScore formats a score without trailing zeros
+func formatScore(score float64) string {
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+// routeRepeat is a route sent on more than one date
+type routeRepeat struct {
+	Color string   `json:"color"`
+	Grade string   `json:"grade"`
+	Meta  string   `json:"meta"`
+	Dates []string `json:"dates"`
+}
+
+// repeatedRoutes groups sends by color, grade and meta, returning the routes
+// sent on more than one distinct date in the order first seen, each with its
+// dates in chronological order
+func repeatedRoutes(sends []parser.Send) []routeRepeat {
+	type route struct{ color, grade, meta string }
+	index := make(map[route]int)
+	var routes []routeRepeat
+
+	for _, send := range sends {
+		if send.Date == "" {
+			continue
+		}
+		key := route{send.Color, send.Grade, strings.TrimSpace(send.Meta)}
+		i, seen := index[key]
+		if !seen {
+			i = len(routes)
+			index[key] = i
+			routes = append(routes, routeRepeat{Color: key.color, Grade: key.grade, Meta: key.meta})
+		}
+		if !slices.Contains(routes[i].Dates, send.Date) {
+			routes[i].Dates = append(routes[i].Dates, send.Date)
+		}
+	}
+
+	repeats := []routeRepeat{}
+	for _, r := range routes {
+		if len(r.Dates) > 1 {
+			sort.Slice(r.Dates, func(i, j int) bool {
+				return dateBefore(r.Dates[i], r.Dates[j])
+			})
+			repeats = append(repeats, r)
+		}
+	}
+	return repeats
+}
+
+// gradeGaps returns the grades never sent between the easiest and hardest
+// grade sent in each system's ladder (V, Font, French and YDS), easiest first
+// Grades outside those ladders, like British or point grades, are ignored
+func gradeGaps(sends []parser.Send) []string {
+	sent := make(map[string]map[int]bool)
+	for _, send := range sends {
+		system, rungs, ok := parser.GradeRungs(send.Grade)
+		if !ok {
+			continue
+		}
+		if sent[system] == nil {
+			sent[system] = make(map[int]bool)
+		}
+		for _, rung := range rungs {
+			sent[system][rung] = true
+		}
+	}
+
+	gaps := []string{}
+	for system, rungs := range sent {
+		ladder := parser.GradeLadder(system)
+		covered := slices.Collect(maps.Keys(rungs))
+		for i := slices.Min(covered); i < slices.Max(covered); i++ {
+			if !rungs[i] {
+				gaps = append(gaps, ladder[i])
+			}
+		}
+	}
+
+	sort.SliceStable(gaps, func(i, j int) bool {
+		return gradeValue(gaps[i]) < gradeValue(gaps[j])
+	})
+	return gaps
+}
+
+// sendMonth returns the year and month of a send's date as YYYY-MM, or
+// "unknown" if the date is missing or unparseable
+func sendMonth(send parser.Send) string {
+	t, err := time.Parse(dateLayout, send.Date)
+	if err != nil {
+		return "unknown"
+	}
+	return t.Format("2006-01")
+}
+
+// sendWeek returns the ISO 8601 year and week of a send's date as YYYY-Www,
+// or "unknown" if the date is missing or unparseable
+func sendWeek(send parser.Send) string {
+	t, err := time.Parse(dateLayout, send.Date)
+	if err != nil {
+		return "unknown"
+	}
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// sortPeriods orders period counts by label, which sorts YYYY-MM and YYYY-Www
+// chronologically, placing "unknown" last
+func sortPeriods(counts []labelCount) {
+	sort.SliceStable(counts, func(i, j int) bool {
+		if (counts[i].Label == "unknown") != (counts[j].Label == "unknown") {
+			return counts[j].Label == "unknown"
+		}
+		return counts[i].Label < counts[j].Label
+	})
+}
+
+// writeCounts outputs label counts in the given format
+// name is used as the label's JSON field and table column
+func writeCounts(w io.Writer, format, name string, counts []labelCount) {
+	switch format {
+	case "json":
+		objects := make([]map[string]any, 0, len(counts))
+		for _, c := range counts {
+			objects = append(objects, map[string]any{name: c.Label, "count": c.Count})
+		}
+		writeJSON(w, objects)
+	case "text":
+		for _, c := range counts {
+			fmt.Fprintf(w, "%7d %s\n", c.Count, c.Label)
+		}
+	default:
+		records := [][]string{{name, "count"}}
+		for _, c := range counts {
+			records = append(records, []string{c.Label, strconv.Itoa(c.Count)})
+		}
+		writeRecords(w, format, records)
+	}
+}
+
+// printPyramid prints a horizontal bar chart of grade counts scaled to width
+// Counts are expected in ascending grade order and printed hardest first so the
+// pyramid reads bottom-to-top
+func printPyramid(w io.Writer, counts []GradeCount, width int) {
+	maxCount := 0
+	labelWidth := 0
+	for _, c := range counts {
+		maxCount = max(maxCount, c.Count)
+		labelWidth = max(labelWidth, len(c.Grade))
+	}
+	if maxCount == 0 {
+		return
+	}
+
+	for i := len(counts) - 1; i >= 0; i-- {
+		c := counts[i]
+		// Scale so the largest bar fills width, keeping at least one mark per grade
+		bar := max(c.Count*width/maxCount, 1)
+		fmt.Fprintf(w, "%-*s %s %d\n", labelWidth, c.Grade, strings.Repeat("#", bar), c.Count)
+	}
+}
+
+// printStats prints a summary of total sends, flashes and onsights
+func printStats(w io.Writer, sends []parser.Send) {
+	var flashes []parser.Send
+	onsights := 0
+	for _, send := range sends {
+		switch send.Style {
+		case "flash":
+			flashes = append(flashes, send)
+		case "onsight":
+			onsights++
+		}
+	}
+
+	percent := func(n int) float64 {
+		if len(sends) == 0 {
+			return 0
+		}
+		return float64(n) * 100 / float64(len(sends))
+	}
+
+	hardest := "-"
+	if len(flashes) > 0 {
+		hardest = extremeSend(flashes, false).Grade
+	}
+
+	fmt.Fprintf(w, "%-14s %6d\n", "sends", len(sends))
+	fmt.Fprintf(w, "%-14s %6d %6.1f%%\n", "flashes", len(flashes), percent(len(flashes)))
+	fmt.Fprintf(w, "%-14s %6d %6.1f%%\n", "onsights", onsights, percent(onsights))
+	fmt.Fprintf(w, "%-14s %6s\n", "hardest flash", hardest)
+}
+
+// dateSpan is a run of days from Start to End, such as a streak of
+// consecutive send days
+type dateSpan struct {
+	Days  int    `json:"days"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// longestStreak returns the longest run of consecutive calendar days with a
+// send; the earliest run wins a tie and unparseable dates are ignored
+func longestStreak(sends []parser.Send) dateSpan {
+	seen := make(map[time.Time]bool)
+	var days []time.Time
+	for _, send := range sends {
+		t, err := time.Parse(dateLayout, send.Date)
+		if err != nil || seen[t] {
+			continue
+		}
+		seen[t] = true
+		days = append(days, t)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+
+	var best dateSpan
+	start := 0
+	for i := range days {
+		if i > 0 && !days[i-1].AddDate(0, 0, 1).Equal(days[i]) {
+			start = i
+		}
+		if n := i - start + 1; n > best.Days {
+			best = dateSpan{Days: n, Start: days[start].Format(dateLayout), End: days[i].Format(dateLayout)}
+		}
+	}
+	return best
+}
+
+// mergeSends returns a copy of sends with boulder and rope grades merged into
+// their canonical V-grade, sorted by the merged grade
+func mergeSends(sends []parser.Send) []parser.Send {
+	merged := make([]parser.Send, len(sends))
+	for i, send := range sends {
+		send.Grade = parser.MergeGrade(send.Grade)
+		merged[i] = send
+	}
+	sortSends(merged, "grade", false)
+	return merged
+}
+
+// calendarShades are the cells printCalendar uses for 0, 1, 2-3 and 4 or
+// more sends in a day
+var calendarShades = []string{" ", ".", ":", "#"}
+
+// printCalendar prints a grid of the days in year with weeks as columns and
+// weekdays as rows, shading each day by its number of sends
+func printCalendar(w io.Writer, sends []parser.Send, year int) {
+	perDay := make(map[string]int)
+	for _, send := range sends {
+		if t, err := time.Parse(dateLayout, send.Date); err == nil && t.Year() == year {
+			perDay[t.Format(dateLayout)]++
+		}
+	}
+
+	// Columns start on Mondays, so the first week may begin in the previous year
+	first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	lead := (int(first.Weekday()) + 6) % 7
+	days := first.AddDate(1, 0, 0).Sub(first).Hours() / 24
+	weeks := (lead + int(days) + 6) / 7
+
+	// Month initials above the week each month starts in
+	header := []byte(strings.Repeat(" ", weeks))
+	for m := time.January; m <= time.December; m++ {
+		day := time.Date(year, m, 1, 0, 0, 0, 0, time.UTC).YearDay() - 1
+		header[(lead+day)/7] = m.String()[0]
+	}
+	fmt.Fprintf(w, "    %s\n", strings.TrimRight(string(header), " "))
+
+	for weekday := 0; weekday < 7; weekday++ {
+		var row strings.Builder
+		for week := 0; week < weeks; week++ {
+			t := first.AddDate(0, 0, week*7+weekday-lead)
+			if t.Year() != year {
+				row.WriteString(" ")
+				continue
+			}
+			n := perDay[t.Format(dateLayout)]
+			switch {
+			case n >= 4:
+				n = 3
+			case n >= 2:
+				n = 2
+			}
+			row.WriteString(calendarShades[n])
+		}
+		label := time.Weekday((weekday + 1) % 7).String()[:3]
+		fmt.Fprintf(w, "%s %s\n", label, strings.TrimRight(row.String(), " "))
+	}
+}
+
+// convertSends returns a copy of sends with grades converted to system for display
+// An empty system returns sends unchanged
+func convertSends(sends []parser.Send, system string) []parser.Send {
+	if system == "" {
+		return sends
+	}
+
+	converted := make([]parser.Send, len(sends))
+	for i, send := range sends {
+		send.Grade, _ = parser.ConvertGrade(send.Grade, system)
+		converted[i] = send
+	}
+	return converted
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sends/parser"
+)
+
+// gradesOrder maps grades from --grades-order to their position in the file
+var gradesOrder map[string]int
+
+// gradeAliases maps retired grade labels from --aliases to their canonical grade
+var gradeAliases map[string]string
+
+// gradePoints maps grades from --points to the score each send of them earns
+var gradePoints map[string]float64
+
+// canonicalGrade returns the grade an alias stands for, or grade itself
+func canonicalGrade(grade string) string {
+	if canonical, ok := gradeAliases[parser.NormalizeGrade(grade)]; ok {
+		return canonical
+	}
+	return grade
+}
+
+// gradeValue returns the sort value for a grade
+// Grades listed in --grades-order sort before all others in file order;
+// everything else falls back to parser.ParseGrade
+// An alias to a label ParseGrade can't order takes the place of the lowest
+// grade it replaces, so "900 = Level 3" still sorts with the point grades
+func gradeValue(grade string) float64 {
+	grade = canonicalGrade(grade)
+	value, ok := orderedValue(grade)
+	if ok || parser.Discipline(grade) != "unknown" {
+		return value
+	}
+
+	canonical := parser.NormalizeGrade(grade)
+	found := false
+	for old, to := range gradeAliases {
+		if to != canonical {
+			continue
+		}
+		if v, _ := orderedValue(old); !found || v < value {
+			value, found = v, true
+		}
+	}
+	return value
+}
+
+// orderedValue returns a grade's position in --grades-order, reporting
+// whether it's listed, or else its parser.ParseGrade value
+func orderedValue(grade string) (float64, bool) {
+	if i, ok := gradesOrder[parser.NormalizeGrade(grade)]; ok {
+		return float64(i - len(gradesOrder) - 1000000), true
+	}
+	return parser.ParseGrade(grade), false
+}
+
+// loadGradesOrder reads grades from path, one per line in ascending order
+func loadGradesOrder(path string) (map[string]int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	order := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		grade := parser.NormalizeGrade(strings.TrimSpace(scanner.Text()))
+		if _, seen := order[grade]; grade != "" && !seen {
+			order[grade] = len(order)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// loadGradeAliases reads "old = canonical" grade pairs from path, one per line
+func loadGradeAliases(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	aliases := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		old, canonical, ok := strings.Cut(line, "=")
+		old, canonical = strings.TrimSpace(old), strings.TrimSpace(canonical)
+		if !ok || old == "" || canonical == "" {
+			return nil, fmt.Errorf("line %d: expected old = canonical", n)
+		}
+		aliases[parser.NormalizeGrade(old)] = parser.NormalizeGrade(canonical)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return aliases, nil
+}
+
+// loadGradePoints reads "grade = points" pairs from path, one per line
+func loadGradePoints(path string) (map[string]float64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	points := make(map[string]float64)
+	scanner := bufio.NewScanner(file)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		grade, value, ok := strings.Cut(line, "=")
+		grade = strings.TrimSpace(grade)
+		p, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if !ok || grade == "" || err != nil {
+			return nil, fmt.Errorf("line %d: expected grade = points", n)
+		}
+		points[parser.NormalizeGrade(grade)] = p
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
+
+// sortSends sorts sends in place by key ("grade", "date" or "color")
+// Sends are always ordered by grade, then color first so ties on other keys stay in grade order
+func sortSends(sends []parser.Send, key string, reverse bool) {
+	var less func(a, b parser.Send) bool
+	switch key {
+	case "date":
+		less = func(a, b parser.Send) bool { return dateBefore(a.Date, b.Date) }
+	case "color":
+		less = func(a, b parser.Send) bool { return a.Color < b.Color }
+	}
+
+	byGrade := func(a, b parser.Send) bool {
+		ga := gradeValue(a.Grade)
+		gb := gradeValue(b.Grade)
+		if ga != gb {
+			return ga < gb
+		}
+		return a.Color < b.Color
+	}
+
+	if less == nil {
+		less = byGrade
+	} else {
+		sort.SliceStable(sends, func(i, j int) bool { return byGrade(sends[i], sends[j]) })
+	}
+
+	sort.SliceStable(sends, func(i, j int) bool {
+		if reverse {
+			return less(sends[j], sends[i])
+		}
+		return less(sends[i], sends[j])
+	})
+}
+
+// dateBefore reports whether date a is chronologically before date b
+func dateBefore(a, b string) bool {
+	ta, erra := time.Parse(dateLayout, a)
+	tb, errb := time.Parse(dateLayout, b)
+	// If parsing fails, fall back to string comparison
+	if erra != nil || errb != nil {
+		return a < b
+	}
+	return ta.Before(tb)
+}
+
+// extremeSend returns the highest graded send, or the lowest if lowest is set
+// Ties are broken by date: most recent for the highest, earliest for the lowest
+func extremeSend(sends []parser.Send, lowest bool) parser.Send {
+	best := sends[0]
+	bestGrade := gradeValue(best.Grade)
+
+	for _, send := range sends[1:] {
+		g := gradeValue(send.Grade)
+		if lowest {
+			if g < bestGrade || (g == bestGrade && dateBefore(send.Date, best.Date)) {
+				best, bestGrade = send, g
+			}
+		} else {
+			if g > bestGrade || (g == bestGrade && dateBefore(best.Date, send.Date)) {
+				best, bestGrade = send, g
+			}
+		}
+	}
+
+	return best
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"sends/parser"
+)
+
+func TestGradeValueUnparseableAlias(t *testing.T) {
+	gradeAliases = map[string]string{"900": "Level 3"}
+	t.Cleanup(func() { gradeAliases = nil })
+
+	sends := []parser.Send{
+		{Color: "red", Grade: "1000"},
+		{Color: "blue", Grade: canonicalGrade("900")},
+		{Color: "green", Grade: "800"},
+		{Color: "pink", Grade: "Level 3"},
+		{Color: "black", Grade: "Mystery"},
+	}
+	sortSends(sends, "grade", false)
+
+	var got []string
+	for _, send := range sends {
+		got = append(got, send.Grade)
+	}
+	want := []string{"800", "Level 3", "Level 3", "1000", "Mystery"}
+	if !slices.Equal(got, want) {
+		t.Errorf("sorted grades = %q, want %q", got, want)
+	}
+
+	counts := countGrades(sends)
+	if len(counts) != 4 || counts[1] != (GradeCount{Grade: "Level 3", Count: 2}) {
+		t.Errorf("countGrades = %+v, want Level 3 counted once with 2 sends", counts)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// watchInterval is how often watch mode polls content files for changes
+const watchInterval = time.Second
+
+// watch runs once, then polls the content files and re-runs whenever one is
+// added, removed or modified, until interrupted
+func watch(o *options) {
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		paths, _ := collectPaths(o)
+		if state := contentState(paths); state != last {
+			last = state
+
+			// Clear the screen between updates, unless the output goes elsewhere
+			if o.outputPath == "" && isTerminal(os.Stdout) {
+				fmt.Print("\033[H\033[2J")
+			}
+			run(o)
+		}
+
+		select {
+		case <-interrupt:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// contentState summarizes the paths and their modification times so changes
+// can be detected by comparing snapshots
+func contentState(paths []string) string {
+	var b strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}